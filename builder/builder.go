@@ -1,9 +1,17 @@
 package builder
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 )
 
+// MaxParams is the maximum number of bind parameters PostgreSQL accepts in a single statement.
+const MaxParams = 65535
+
+// ErrTooManyParams is returned by Build when the generated statement has more than MaxParams parameters.
+var ErrTooManyParams = errors.New("too many parameters")
+
 // Builder interface is implemented by all specialized builders below and is used to
 // generate SQL statements.
 type Builder interface {
@@ -14,6 +22,7 @@ type Builder interface {
 // Selecter is a SELECT statement builder.
 type Selecter interface {
 	Builder
+	Describer
 	With(name string, q Builder) Selecter
 	Columns(col string, params ...interface{}) Selecter
 	From(from string, params ...interface{}) Selecter
@@ -24,13 +33,14 @@ type Selecter interface {
 	Distinct(distinct ...string) Selecter
 	GroupBy(groupBy string) Selecter
 	Having(having string, params ...interface{}) Selecter
-	OrderBy(orderBy string) Selecter
+	OrderBy(orderBy string, params ...interface{}) Selecter
 	For(locking string) Selecter
 }
 
 // Updater is an UPDATE statement builder.
 type Updater interface {
 	Builder
+	Describer
 	With(name string, q Builder) Updater
 	From(from string, params ...interface{}) Updater
 	Set(set string, params ...interface{}) Updater
@@ -38,12 +48,23 @@ type Updater interface {
 	Returning(returning ...string) Updater
 }
 
+// Chunker is implemented by builders which can split their VALUES rows
+// into several statements.
+type Chunker interface {
+	Builder
+	// Chunks returns builders with at most size VALUES rows each.
+	Chunks(size int) ([]Builder, error)
+}
+
 // Inserter is an INSERT statement builder.
 type Inserter interface {
 	Builder
+	Describer
+	Chunks(size int) ([]Builder, error)
 	With(name string, q Builder) Inserter
 	Columns(col ...string) Inserter
 	Values(params ...interface{}) Inserter
+	Unnest(types ...string) Inserter // pass values as one array per column, types are inferred unless specified
 	From(q Selecter) Inserter
 	OnConflictDoNothing(target string, params ...interface{}) Inserter
 	Returning(returning ...string) Inserter
@@ -51,6 +72,7 @@ type Inserter interface {
 
 type Insecter interface {
 	Builder
+	Describer
 	Columns(col ...string) Insecter
 	Values(params ...interface{}) Insecter
 	Where(where string, params ...interface{}) Insecter
@@ -60,9 +82,12 @@ type Insecter interface {
 // Upserter is an INSERT statement builder.
 type Upserter interface {
 	Builder
+	Describer
+	Chunks(size int) ([]Builder, error)
 	With(name string, q Builder) Upserter
 	Columns(col ...string) Upserter
 	Values(params ...interface{}) Upserter
+	Unnest(types ...string) Upserter // pass values as one array per column, types are inferred unless specified
 	From(q Selecter) Upserter
 	Update(update string, params ...interface{}) Upserter // unless specified, Columns with EXCLUDED values used
 	Returning(returning ...string) Upserter
@@ -71,6 +96,7 @@ type Upserter interface {
 // Deleter is a DELETE statement builder.
 type Deleter interface {
 	Builder
+	Describer
 	With(name string, q Builder) Deleter
 	Using(using string) Deleter
 	Where(where string, params ...interface{}) Deleter
@@ -115,6 +141,33 @@ func (dv DefaultValue) String() string {
 	return "<DEFAULT>"
 }
 
+// ExprValue is an SQL expression with its own parameters. When passed to Values or
+// used as a parameter, it is rendered inline instead of a placeholder.
+type ExprValue struct {
+	text   string
+	params []interface{}
+}
+
+// Expr returns an ExprValue for the given expression, e.g. Expr("now()") or
+// Expr("nextval($1)", "users_id_seq"). Expression placeholders are renumbered
+// relative to the enclosing statement.
+func Expr(text string, params ...interface{}) ExprValue {
+	return ExprValue{text, params}
+}
+
+// TuplesValue is a slice of structs expanded into row values of the given columns.
+type TuplesValue struct {
+	slice   interface{}
+	columns []string
+}
+
+// Tuples returns a TuplesValue for slice, which must be a slice of structs or pointers
+// to structs. Struct fields are mapped to columns using "db" tags like sqlx does, e.g.
+// Where("(tenant_id, id) IN ($1)", Tuples(keys, "tenant_id", "id")).
+func Tuples(slice interface{}, columns ...string) TuplesValue {
+	return TuplesValue{slice, columns}
+}
+
 func Default(value interface{}) interface{} {
 	val := reflect.ValueOf(value)
 	switch value.(type) {
@@ -152,3 +205,23 @@ func Default(value interface{}) interface{} {
 
 	return value
 }
+
+func checkParams(params []interface{}) error {
+	if len(params) > MaxParams {
+		return fmt.Errorf("%w: statement has %d, PostgreSQL allows at most %d", ErrTooManyParams, len(params), MaxParams)
+	}
+	return nil
+}
+
+// chunkRows splits rows into consecutive chunks with at most size rows each.
+func chunkRows(rows [][]interface{}, size int) ([][][]interface{}, error) {
+	if size < 1 {
+		return nil, errors.New("chunk size should be >= 1")
+	}
+	var res [][][]interface{}
+	for len(rows) > size {
+		res = append(res, rows[:size:size])
+		rows = rows[size:]
+	}
+	return append(res, rows), nil
+}
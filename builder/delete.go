@@ -34,11 +34,23 @@ func (b *deleter) Returning(returning ...string) Deleter {
 }
 
 func (b *deleter) Build() (string, []interface{}, error) {
+	return b.BuildDialect(Postgres)
+}
+
+func (b *deleter) BuildDialect(d Dialect) (string, []interface{}, error) {
 	// verify
 	if isBlank(b.from) {
 		return "", nil, errors.New("empty from")
 	}
 
+	if len(b.using) > 0 && d != Postgres {
+		return "", nil, d.unsupported("DELETE ... USING")
+	}
+
+	if err := d.returning(b.returning); err != nil {
+		return "", nil, err
+	}
+
 	// build
 	var params []interface{}
 	var buf bytes.Buffer
@@ -77,12 +89,13 @@ func (b *deleter) Build() (string, []interface{}, error) {
 	// where
 	if len(b.where) > 0 {
 		// validate and rename where conditions
-		if err := b.where.build(len(params) + 1); err != nil {
+		where, err := b.where.build(len(params) + 1)
+		if err != nil {
 			return "", nil, err
 		}
 
 		buf.WriteString(" WHERE (")
-		for i, x := range b.where {
+		for i, x := range where {
 			if i > 0 {
 				buf.WriteString(") AND (")
 			}
@@ -103,5 +116,8 @@ func (b *deleter) Build() (string, []interface{}, error) {
 		}
 	}
 
-	return buf.String(), params, nil
+	if err := checkParams(params); err != nil {
+		return "", nil, err
+	}
+	return d.rebind(buf.String(), params)
 }
@@ -0,0 +1,268 @@
+package builder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect is an SQL dialect used to generate statements. Builders generate PostgreSQL
+// statements by default, other dialects are supported with BuildDialect.
+type Dialect int
+
+const (
+	// Postgres uses $N placeholders and "quoted" identifiers.
+	Postgres Dialect = iota
+	// SQLite uses ? placeholders and "quoted" identifiers.
+	SQLite
+	// MySQL uses ? placeholders and `quoted` identifiers. Upserts are generated with
+	// ON DUPLICATE KEY UPDATE and RETURNING is not available.
+	MySQL
+)
+
+// ErrUnsupported is returned by BuildDialect when the statement uses a feature which
+// is not available in the dialect.
+var ErrUnsupported = errors.New("unsupported feature")
+
+func (d Dialect) String() string {
+	switch d {
+	case Postgres:
+		return "postgres"
+	case SQLite:
+		return "sqlite"
+	case MySQL:
+		return "mysql"
+	}
+	return "Dialect(" + strconv.Itoa(int(d)) + ")"
+}
+
+// DialectFor returns the dialect for database/sql driver name. Unknown drivers are
+// assumed to be PostgreSQL compatible.
+func DialectFor(driverName string) Dialect {
+	switch driverName {
+	case "sqlite", "sqlite3":
+		return SQLite
+	case "mysql":
+		return MySQL
+	}
+	return Postgres
+}
+
+// Ident returns a quoted identifier built from parts using the dialect quoting rules.
+func (d Dialect) Ident(parts ...string) string {
+	if d != MySQL {
+		return Ident(parts...)
+	}
+	var sb strings.Builder
+	for i, p := range parts {
+		if i > 0 {
+			sb.WriteRune('.')
+		}
+		sb.WriteRune('`')
+		sb.WriteString(strings.ReplaceAll(p, "`", "``"))
+		sb.WriteRune('`')
+	}
+	return sb.String()
+}
+
+// Returning returns true if the dialect supports RETURNING clause.
+func (d Dialect) Returning() bool {
+	return d != MySQL
+}
+
+// unsupported returns an error for the feature which is not available in the dialect.
+func (d Dialect) unsupported(feature string) error {
+	return fmt.Errorf("%w: %s is not available in %s", ErrUnsupported, feature, d)
+}
+
+// returning returns an error if the dialect does not support RETURNING clause.
+func (d Dialect) returning(returning []string) error {
+	if len(returning) > 0 && !d.Returning() {
+		return d.unsupported("RETURNING")
+	}
+	return nil
+}
+
+// rebind converts $N placeholders of a PostgreSQL statement to the dialect placeholders.
+// As ? placeholders are positional, parameters referenced more than once are repeated.
+// For MySQL, "quoted" identifiers are converted to `quoted` ones, as statements are
+// written using PostgreSQL quoting rules.
+func (d Dialect) rebind(sql string, params []interface{}) (string, []interface{}, error) {
+	if d == Postgres {
+		return sql, params, nil
+	}
+	var res []interface{}
+	var err error
+	text := rewriteWords(sql, func(w string) string {
+		if d == MySQL && strings.ContainsRune(w, '"') {
+			return backquote(w)
+		}
+		if len(w) < 2 || w[0] != '$' {
+			return w
+		}
+		n, e := strconv.Atoi(w[1:])
+		if e != nil {
+			return w
+		}
+		if n < 1 || n > len(params) {
+			err = fmt.Errorf("invalid placeholder index: %d", n)
+			return w
+		}
+		res = append(res, params[n-1])
+		return "?"
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return text, res, nil
+}
+
+// backquote converts "quoted" identifiers of word w to MySQL `quoted` identifiers.
+func backquote(w string) string {
+	var sb strings.Builder
+	rr := []rune(w)
+	for i := 0; i < len(rr); i++ {
+		if rr[i] != '"' {
+			sb.WriteRune(rr[i])
+			continue
+		}
+		sb.WriteRune('`')
+		for i++; i < len(rr); i++ {
+			if rr[i] == '"' {
+				if i+1 < len(rr) && rr[i+1] == '"' {
+					i++ // escaped double quote
+				} else {
+					break
+				}
+			}
+			if rr[i] == '`' {
+				sb.WriteRune('`')
+			}
+			sb.WriteRune(rr[i])
+		}
+		sb.WriteRune('`')
+	}
+	return sb.String()
+}
+
+// excludedToValues replaces EXCLUDED.col references of ON CONFLICT update statements
+// with MySQL VALUES(col).
+func excludedToValues(s string) string {
+	return rewriteWords(s, func(w string) string {
+		if len(w) > 9 && strings.EqualFold(w[:9], "EXCLUDED.") {
+			return "VALUES(" + w[9:] + ")"
+		}
+		return w
+	})
+}
+
+// rewriteWords replaces each word of s with the result of fn. Words are runs of letters,
+// digits, '_', '.', '$' and "quoted" identifiers, e.g. EXCLUDED."name"; string literals,
+// `quoted` identifiers and comments are copied as-is.
+func rewriteWords(s string, fn func(w string) string) string {
+	var sb strings.Builder
+	rr := []rune(s)
+	for i := 0; i < len(rr); {
+		r := rr[i]
+		switch {
+		case r == '\'' || r == '`':
+			start := i
+			for i++; i < len(rr) && rr[i] != r; i++ {
+				if r == '\'' && rr[i] == '\\' {
+					i++
+				}
+			}
+			if i < len(rr) {
+				i++
+			}
+			if i > len(rr) {
+				i = len(rr)
+			}
+			sb.WriteString(string(rr[start:i]))
+		case r == '-' && i+1 < len(rr) && rr[i+1] == '-':
+			start := i
+			for i < len(rr) && rr[i] != '\n' {
+				i++
+			}
+			sb.WriteString(string(rr[start:i]))
+		case r == '/' && i+1 < len(rr) && rr[i+1] == '*':
+			start := i
+			for i += 2; i < len(rr) && !(rr[i] == '*' && i+1 < len(rr) && rr[i+1] == '/'); i++ {
+			}
+			if i += 2; i > len(rr) {
+				i = len(rr)
+			}
+			sb.WriteString(string(rr[start:i]))
+		case isWordRune(r) || r == '"':
+			start := i
+			for i < len(rr) && (isWordRune(rr[i]) || rr[i] == '"') {
+				if rr[i] != '"' {
+					i++
+					continue
+				}
+				// quoted identifier, escaped double quotes are doubled
+				for i++; i < len(rr); i++ {
+					if rr[i] == '"' {
+						if i+1 < len(rr) && rr[i+1] == '"' {
+							i++
+							continue
+						}
+						break
+					}
+				}
+				if i < len(rr) {
+					i++
+				}
+			}
+			sb.WriteString(fn(string(rr[start:i])))
+		default:
+			sb.WriteRune(r)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// DialectBuilder is implemented by builders which can generate statements for
+// dialects other than PostgreSQL.
+type DialectBuilder interface {
+	Builder
+	// BuildDialect returns generated SQL and parameters for the dialect.
+	BuildDialect(d Dialect) (string, []interface{}, error)
+}
+
+// BuildDialect returns SQL and parameters generated by b for the dialect. Builders which
+// do not implement DialectBuilder are built for PostgreSQL and their placeholders
+// are converted.
+func BuildDialect(d Dialect, b Builder) (string, []interface{}, error) {
+	if db, ok := b.(DialectBuilder); ok {
+		return db.BuildDialect(d)
+	}
+	sql, params, err := b.Build()
+	if err != nil {
+		return "", nil, err
+	}
+	return d.rebind(sql, params)
+}
+
+// WithDialect returns a builder which builds b for the dialect.
+func WithDialect(d Dialect, b Builder) Builder {
+	if d == Postgres {
+		return b
+	}
+	return &dialecter{d, b}
+}
+
+type dialecter struct {
+	dialect Dialect
+	b       Builder
+}
+
+func (b *dialecter) Build() (string, []interface{}, error) {
+	return BuildDialect(b.dialect, b.b)
+}
+
+func (b *dialecter) Describe() Info {
+	return Describe(b.b)
+}
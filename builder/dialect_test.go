@@ -0,0 +1,187 @@
+package builder
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDialect(t *testing.T) {
+	examples := []struct {
+		name     string
+		dialect  Dialect
+		b        Builder
+		expected string
+		params   []interface{}
+	}{
+		{
+			"Postgres",
+			Postgres,
+			Select("a").From("t").Where("a = $1 OR b = $1", 1).Offset(10),
+			"SELECT a FROM t WHERE (a = $1 OR b = $1) OFFSET 10",
+			[]interface{}{1},
+		},
+		{
+			"SQLiteRepeatedParams",
+			SQLite,
+			Select("a").From("t").Where("a = $1 OR b = $1", 1).Where("c IN ($1)", []int{2, 3}),
+			"SELECT a FROM t WHERE (a = ? OR b = ?) AND (c IN (?,?))",
+			[]interface{}{1, 1, 2, 3},
+		},
+		{
+			"SQLiteQuotedPlaceholders",
+			SQLite,
+			SQL("SELECT '$1', a FROM t WHERE a = $1", 1),
+			"SELECT '$1', a FROM t WHERE a = ?",
+			[]interface{}{1},
+		},
+		{
+			"SQLiteOffset",
+			SQLite,
+			Select("a").From("t").Offset(10),
+			"SELECT a FROM t LIMIT -1 OFFSET 10",
+			nil,
+		},
+		{
+			"MySQLLimitOffset",
+			MySQL,
+			Select("a").From("t").Offset(10).Limit(5),
+			"SELECT a FROM t LIMIT 5 OFFSET 10",
+			nil,
+		},
+		{
+			"SQLiteUpsert",
+			SQLite,
+			Upsert("t", "(a)").Columns("a", "b").Values(1, 2).Returning("id"),
+			"INSERT INTO t (a, b) VALUES (?, ?) ON CONFLICT (a) DO UPDATE SET a = EXCLUDED.a, b = EXCLUDED.b RETURNING id",
+			[]interface{}{1, 2},
+		},
+		{
+			"MySQLUpsert",
+			MySQL,
+			Upsert("t", "(a)").Columns("a", "b").Values(1, 2),
+			"INSERT INTO t (a, b) VALUES (?, ?) ON DUPLICATE KEY UPDATE a = VALUES(a), b = VALUES(b)",
+			[]interface{}{1, 2},
+		},
+		{
+			"MySQLUpsertUpdate",
+			MySQL,
+			Upsert("t", "(a)").Columns("a", "b").Values(1, 2).Update("b = excluded.b + $1, c = 'EXCLUDED.c'", 3),
+			"INSERT INTO t (a, b) VALUES (?, ?) ON DUPLICATE KEY UPDATE b = VALUES(b) + ?, c = 'EXCLUDED.c'",
+			[]interface{}{1, 2, 3},
+		},
+		{
+			"MySQLInsertDoNothing",
+			MySQL,
+			Insert("t").Columns("a", "b").Values(1, 2).OnConflictDoNothing("(a)"),
+			"INSERT INTO t (a, b) VALUES (?, ?) ON DUPLICATE KEY UPDATE a = a",
+			[]interface{}{1, 2},
+		},
+		{
+			"MySQLUpdate",
+			MySQL,
+			Update("t").Set("a = $1", 1).Where("id = $1", 2),
+			"UPDATE t SET a = ? WHERE (id = ?)",
+			[]interface{}{1, 2},
+		},
+		{
+			"MySQLIdent",
+			MySQL,
+			Upsert(Ident("users"), "("+Ident("email")+")").Columns(Ident("email"), Ident("name")).Values("a@b.c", "a"),
+			"INSERT INTO `users` (`email`, `name`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `email` = VALUES(`email`), `name` = VALUES(`name`)",
+			[]interface{}{"a@b.c", "a"},
+		},
+		{
+			"MySQLIdentOrderBy",
+			MySQL,
+			Select("a").From(Ident("public", "t")).Where(`"x""y" = $1 AND b = '"z"'`, 1).OrderBy(Ident("order")),
+			"SELECT a FROM `public`.`t` WHERE (`x\"y` = ? AND b = '\"z\"') ORDER BY `order`",
+			[]interface{}{1},
+		},
+		{
+			"MySQLUpsertUpdateIdent",
+			MySQL,
+			Upsert("t", "(a)").Columns("a", "name").Values(1, 2).Update(`"name" = EXCLUDED."name", "a` + "`" + `b" = excluded."a"`),
+			"INSERT INTO t (a, name) VALUES (?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `a``b` = VALUES(`a`)",
+			[]interface{}{1, 2},
+		},
+	}
+	for _, x := range examples {
+		t.Run(x.name, func(t *testing.T) {
+			sql, params, err := BuildDialect(x.dialect, x.b)
+			if err != nil {
+				t.Fatalf("expected err to be nil, got %v", err)
+			}
+			if err := validateBuilderResult(sql, x.expected, len(params), len(x.params)); err != nil {
+				t.Error(err)
+			}
+			if len(x.params) > 0 && !reflect.DeepEqual(params, x.params) {
+				t.Errorf("expected params %v, got %v", x.params, params)
+			}
+		})
+	}
+}
+
+func TestDialectUnsupported(t *testing.T) {
+	examples := []struct {
+		name    string
+		dialect Dialect
+		b       Builder
+	}{
+		{"MySQLReturning", MySQL, Insert("t").Columns("a").Values(1).Returning("id")},
+		{"MySQLUpdateFrom", MySQL, Update("t").Set("a = 1").From("t2")},
+		{"MySQLInsect", MySQL, Insect("t").Columns("a").Values(1)},
+		{"SQLiteDistinctOn", SQLite, Select("a").Distinct("a").From("t")},
+		{"SQLiteForUpdate", SQLite, Select("a").From("t").For("UPDATE")},
+		{"SQLiteDeleteUsing", SQLite, Delete("t").Using("t2")},
+		{"SQLiteUnnest", SQLite, Insert("t").Columns("a").Values(1).Unnest()},
+	}
+	for _, x := range examples {
+		t.Run(x.name, func(t *testing.T) {
+			_, _, err := BuildDialect(x.dialect, x.b)
+			if !errors.Is(err, ErrUnsupported) {
+				t.Errorf("expected ErrUnsupported, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	examples := map[string]Dialect{
+		"postgres": Postgres,
+		"pgx":      Postgres,
+		"sqlite3":  SQLite,
+		"mysql":    MySQL,
+	}
+	for driver, expected := range examples {
+		if d := DialectFor(driver); d != expected {
+			t.Errorf("%s: expected %v, got %v", driver, expected, d)
+		}
+	}
+
+	if s := MySQL.Ident("db", "my`table"); s != "`db`.`my``table`" {
+		t.Errorf("unexpected MySQL identifier %s", s)
+	}
+	if s := SQLite.Ident("t"); s != `"t"` {
+		t.Errorf("unexpected SQLite identifier %s", s)
+	}
+}
+
+func TestWithDialect(t *testing.T) {
+	b := Select("a").From("t").Where("a = $1", 1)
+	if WithDialect(Postgres, b) != Builder(b) {
+		t.Error("expected Postgres builder to be returned as-is")
+	}
+
+	wb := WithDialect(MySQL, b)
+	sql, _, err := wb.Build()
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %v", err)
+	}
+	if expected := "SELECT a FROM t WHERE (a = ?)"; sql != expected {
+		t.Errorf("expected sql %q, got %q", expected, sql)
+	}
+	if info := Describe(wb); info.Kind != KindSelect || len(info.Tables) != 1 {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
@@ -2,6 +2,7 @@ package builder
 
 import (
 	"bytes"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"reflect"
@@ -23,6 +24,9 @@ func (x *expr) build(startIdx int) (int, error) {
 	var paramIdx int
 	var newParams []interface{}
 
+	// rendered placeholders by parameter index, repeated references reuse them
+	rendered := make(map[int]string)
+
 	rr := []rune(x.text)
 
 	for idx := 0; idx < len(rr); {
@@ -58,6 +62,23 @@ func (x *expr) build(startIdx int) (int, error) {
 			if !found {
 				return 0, errors.New("missing closing quote")
 			}
+		case '"':
+			// quoted identifier, escaped double quotes are doubled and
+			// handled as two adjacent identifiers
+			buf.WriteRune(rr[idx])
+			idx++
+			found := false
+			for ; idx < len(rr); idx++ {
+				buf.WriteRune(rr[idx])
+				if rr[idx] == '"' {
+					found = true
+					idx++
+					break
+				}
+			}
+			if !found {
+				return 0, errors.New("missing closing quote")
+			}
 		case '$':
 			idx++
 			var b bytes.Buffer
@@ -76,21 +97,34 @@ func (x *expr) build(startIdx int) (int, error) {
 				return 0, fmt.Errorf("invalid placeholder index: %d", pi)
 			}
 			pi -= 1 // placeholder index is one-based
-			m := getSliceMeta(x.params[pi])
-			if m != nil {
+			if r, ok := rendered[pi]; ok {
+				// placeholder is already rendered, refer to the same parameters
+				buf.WriteString(r)
+				continue
+			}
+			mark := buf.Len()
+			if ev, ok := x.params[pi].(ExprValue); ok {
+				// current placeholder is an expression, render it inline
+				sub, err := ev.build(startIdx + paramIdx)
+				if err != nil {
+					return 0, err
+				}
+				buf.WriteString(sub.text)
+				newParams = append(newParams, sub.params...)
+				paramIdx += len(sub.params) // set next parameter index
+			} else if m, err := getSliceMeta(x.params[pi]); err != nil {
+				return 0, err
+			} else if m != nil {
 				// current placeholder is a slice, expand it
 				if m.length == 0 {
 					return 0, errors.New("empty slice passed as 'IN' parameter")
 				}
-				for i := 0; i < m.length; i++ {
-					if i > 0 {
-						buf.WriteRune(',')
-					}
-					buf.WriteRune('$')
-					buf.WriteString(strconv.Itoa(startIdx + paramIdx + i))
-					newParams = append(newParams, m.v.Index(i).Interface())
+				pps, err := m.expand(&buf, startIdx+paramIdx)
+				if err != nil {
+					return 0, err
 				}
-				paramIdx += m.length // set next parameter index
+				newParams = append(newParams, pps...)
+				paramIdx += len(pps) // set next parameter index
 			} else {
 				// current placeholder is not a slice, just renumber it
 				buf.WriteRune('$')
@@ -98,48 +132,228 @@ func (x *expr) build(startIdx int) (int, error) {
 				newParams = append(newParams, x.params[pi])
 				paramIdx += 1 // set next parameter index
 			}
+			rendered[pi] = string(buf.Bytes()[mark:])
 		default:
 			buf.WriteRune(rr[idx])
 			idx++
 		}
 	}
 
+	for i := range x.params {
+		if _, ok := rendered[i]; !ok {
+			return 0, fmt.Errorf("unreferenced parameter: $%d", i+1)
+		}
+	}
+
 	x.text = buf.String()
 	x.params = newParams
 	return startIdx + len(x.params), nil
 }
 
+// build validates and renumbers a copy of this expression starting with startIdx.
+func (ev ExprValue) build(startIdx int) (*expr, error) {
+	if isBlank(ev.text) {
+		return nil, errors.New("empty expression")
+	}
+	x := &expr{ev.text, ev.params}
+	if _, err := x.build(startIdx); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// buildValue writes a single VALUES item to buf and returns params with the
+// item parameters appended. DefaultValue is written as DEFAULT and ExprValue
+// is written inline.
+func buildValue(buf *bytes.Buffer, v interface{}, params []interface{}) ([]interface{}, error) {
+	switch v := v.(type) {
+	case DefaultValue:
+		buf.WriteString("DEFAULT")
+	case ExprValue:
+		x, err := v.build(len(params) + 1)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(x.text)
+		params = append(params, x.params...)
+	default:
+		params = append(params, v)
+		buf.WriteRune('$')
+		buf.WriteString(strconv.Itoa(len(params)))
+	}
+	return params, nil
+}
+
 type sliceMeta struct {
 	v      reflect.Value
 	length int
+	tuple  bool // elements are tuples expanded into row values
 }
 
-func getSliceMeta(p interface{}) *sliceMeta {
-	v := reflect.ValueOf(p)
-	t := reflectx.Deref(v.Type())
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+func getSliceMeta(p interface{}) (*sliceMeta, error) {
+	if tv, ok := p.(TuplesValue); ok {
+		v, err := tv.rows()
+		if err != nil {
+			return nil, err
+		}
+		return &sliceMeta{v, v.Len(), true}, nil
+	}
+
+	// slices implementing driver.Valuer, such as pq.StringArray, are single values
+	if _, ok := p.(driver.Valuer); ok {
+		return nil, nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(p))
+	if !v.IsValid() {
+		return nil, nil
+	}
+	t := v.Type()
 
 	// []byte is a driver.Value type so it should not be expanded
-	if t.Kind() == reflect.Slice && t != reflect.TypeOf([]byte{}) {
-		return &sliceMeta{v, v.Len()}
+	if t.Kind() == reflect.Slice && !isBytes(t) {
+		return &sliceMeta{v, v.Len(), isTuple(t.Elem())}, nil
 	}
-	return nil
+	return nil, nil
 }
 
-func (xx exprs) build(startIdx int) error {
+// isBytes returns true if t is a slice or array of bytes, such as json.RawMessage or
+// [16]byte of UUIDs, which is a single value rather than a tuple.
+func isBytes(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8
+}
+
+// isTuple returns true if slice elements of type t are expanded into row values.
+// Elements implementing driver.Valuer, such as pq.StringArray, are single values.
+func isTuple(t reflect.Type) bool {
+	if t.Implements(valuerType) || reflect.PtrTo(t).Implements(valuerType) {
+		return false
+	}
+	t = reflectx.Deref(t)
+	if t.Implements(valuerType) || reflect.PtrTo(t).Implements(valuerType) {
+		return false
+	}
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && !isBytes(t)
+}
+
+// expand writes comma separated placeholders for slice elements starting with idx to buf
+// and returns their parameters. Tuple elements are written as parenthesized row values.
+func (m *sliceMeta) expand(buf *bytes.Buffer, idx int) ([]interface{}, error) {
+	var params []interface{}
+	width := -1
+	for i := 0; i < m.length; i++ {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		item := m.v.Index(i)
+		if !m.tuple {
+			params = append(params, item.Interface())
+			buf.WriteRune('$')
+			buf.WriteString(strconv.Itoa(idx + len(params) - 1))
+			continue
+		}
+
+		row := reflect.Indirect(item)
+		if row.Kind() == reflect.Interface {
+			row = reflect.Indirect(row.Elem())
+		}
+		if row.Kind() != reflect.Slice && row.Kind() != reflect.Array {
+			return nil, fmt.Errorf("invalid tuple at index %d", i)
+		}
+		if width < 0 {
+			width = row.Len()
+			if width == 0 {
+				return nil, errors.New("empty tuple")
+			}
+		} else if row.Len() != width {
+			return nil, fmt.Errorf("invalid tuple length, expected %d, got %d", width, row.Len())
+		}
+
+		buf.WriteRune('(')
+		for j := 0; j < width; j++ {
+			if j > 0 {
+				buf.WriteRune(',')
+			}
+			params = append(params, row.Index(j).Interface())
+			buf.WriteRune('$')
+			buf.WriteString(strconv.Itoa(idx + len(params) - 1))
+		}
+		buf.WriteRune(')')
+	}
+	return params, nil
+}
+
+// build validates and renumbers copies of these expressions starting with startIdx,
+// leaving the original expressions intact so that builders can be built repeatedly.
+func (xx exprs) build(startIdx int) (exprs, error) {
 	if startIdx < 1 {
-		return errors.New("start index should be >= 1")
+		return nil, errors.New("start index should be >= 1")
 	}
+	res := make(exprs, 0, len(xx))
 	for _, x := range xx {
 		if isBlank(x.text) {
-			return errors.New("empty expression")
+			return nil, errors.New("empty expression")
 		}
+		x := &expr{x.text, x.params}
 		newIdx, err := x.build(startIdx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		startIdx = newIdx
+		res = append(res, x)
+	}
+	return res, nil
+}
+
+// mapper maps struct fields to columns the same way sqlx does by default.
+var mapper = reflectx.NewMapperFunc("db", strings.ToLower)
+
+// rows returns tuple values as [][]interface{}.
+func (tv TuplesValue) rows() (reflect.Value, error) {
+	if len(tv.columns) == 0 {
+		return reflect.Value{}, errors.New("empty tuple columns")
+	}
+	v := reflect.Indirect(reflect.ValueOf(tv.slice))
+	if v.Kind() != reflect.Slice {
+		return reflect.Value{}, fmt.Errorf("expected slice of structs, got %T", tv.slice)
+	}
+	t := reflectx.Deref(v.Type().Elem())
+	if t.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("expected slice of structs, got %T", tv.slice)
+	}
+
+	tm := mapper.TypeMap(t)
+	fields := make([][]int, len(tv.columns))
+	for i, c := range tv.columns {
+		fi := tm.GetByPath(c)
+		if fi == nil {
+			return reflect.Value{}, fmt.Errorf("missing column %s in %v", c, t)
+		}
+		fields[i] = fi.Index
+	}
+
+	rows := make([][]interface{}, v.Len())
+	for i := range rows {
+		item := reflect.Indirect(v.Index(i))
+		if !item.IsValid() {
+			return reflect.Value{}, fmt.Errorf("nil element at index %d", i)
+		}
+		rows[i] = make([]interface{}, len(fields))
+		for j, idx := range fields {
+			rows[i][j] = reflectx.FieldByIndexesReadOnly(item, idx).Interface()
+		}
+	}
+	return reflect.ValueOf(rows), nil
+}
+
+func (xx exprs) texts() []string {
+	res := make([]string, len(xx))
+	for i, x := range xx {
+		res[i] = x.text
 	}
-	return nil
+	return res
 }
 
 func isBlank(s string) bool {
@@ -1,14 +1,17 @@
 package builder
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
+
+	"github.com/lib/pq"
 )
 
 func TestCondition(t *testing.T) {
 	t.Run("Errors", func(t *testing.T) {
 		t.Run("NegativePlaceholder", func(t *testing.T) {
-			err := exprs{&expr{"and", []interface{}{}}}.build(0)
+			_, err := exprs{&expr{"and", []interface{}{}}}.build(0)
 			if err == nil {
 				t.Fatal("expected error not to be empty")
 			}
@@ -20,7 +23,7 @@ func TestCondition(t *testing.T) {
 		})
 
 		t.Run("EmptyExpression", func(t *testing.T) {
-			err := exprs{&expr{"", []interface{}{}}}.build(1)
+			_, err := exprs{&expr{"", []interface{}{}}}.build(1)
 			if err == nil {
 				t.Fatal("expected error not to be empty")
 			}
@@ -32,7 +35,7 @@ func TestCondition(t *testing.T) {
 		})
 
 		t.Run("MissingClosingQuote", func(t *testing.T) {
-			err := exprs{&expr{"name = '' and ' and", []interface{}{}}}.build(1)
+			_, err := exprs{&expr{"name = '' and ' and", []interface{}{}}}.build(1)
 			if err == nil {
 				t.Fatal("expected error not to be empty")
 			}
@@ -44,7 +47,7 @@ func TestCondition(t *testing.T) {
 		})
 
 		t.Run("InvalidPlaceholder", func(t *testing.T) {
-			err := exprs{&expr{"$ name = '' and $5 and true", []interface{}{}}}.build(1)
+			_, err := exprs{&expr{"$ name = '' and $5 and true", []interface{}{}}}.build(1)
 			if err == nil {
 				t.Fatal("expected error not to be empty")
 			}
@@ -55,8 +58,20 @@ func TestCondition(t *testing.T) {
 			}
 		})
 
+		t.Run("UnreferencedParameter", func(t *testing.T) {
+			_, err := exprs{&expr{"a = $1", []interface{}{1, 2}}}.build(1)
+			if err == nil {
+				t.Fatal("expected error not to be empty")
+			}
+
+			msg := "unreferenced parameter: $2"
+			if err.Error() != msg {
+				t.Errorf("expected error %q, got %q", msg, err.Error())
+			}
+		})
+
 		t.Run("InvalidPlaceholderWithIndex", func(t *testing.T) {
-			err := exprs{&expr{"$3 name = '' and $5 and true", []interface{}{}}}.build(1)
+			_, err := exprs{&expr{"$3 name = '' and $5 and true", []interface{}{}}}.build(1)
 			if err == nil {
 				t.Fatal("expected error not to be empty")
 			}
@@ -83,6 +98,27 @@ func TestCondition(t *testing.T) {
 				"",
 				1,
 			},
+			{
+				3,
+				expr{"a = $1 OR b = $1 OR c = $2", []interface{}{"x", "y"}},
+				expr{"a = $3 OR b = $3 OR c = $4", []interface{}{"x", "y"}},
+				"",
+				5,
+			},
+			{
+				1,
+				expr{"a IN ($1) OR b IN ($1) OR c = $2 OR d = $2", []interface{}{[]int{1, 2}, Expr("now() - $1", "1 day")}},
+				expr{"a IN ($1,$2) OR b IN ($1,$2) OR c = now() - $3 OR d = now() - $3", []interface{}{1, 2, "1 day"}},
+				"",
+				4,
+			},
+			{
+				1,
+				expr{"a = $1 AND b = '$2'", []interface{}{"x", "y"}},
+				expr{},
+				"unreferenced parameter: $2",
+				0,
+			},
 		}
 
 		for i, x := range examples {
@@ -192,4 +228,103 @@ func TestCondition(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("Tuples", func(t *testing.T) {
+		type key struct {
+			TenantID int64 `db:"tenant_id"`
+			ID       int64
+		}
+
+		examples := []struct {
+			startIdx       int
+			cond           expr
+			expected       expr
+			expectedError  string
+			expectedNewIdx int
+		}{
+			{
+				1,
+				expr{"(tenant_id, id) IN ($1)", []interface{}{[][]interface{}{{1, 2}, {3, 4}}}},
+				expr{"(tenant_id, id) IN (($1,$2),($3,$4))", []interface{}{1, 2, 3, 4}},
+				"",
+				5,
+			},
+			{
+				3,
+				expr{"name = $1 AND (tenant_id, id) IN ($2)", []interface{}{"name", [][2]int64{{1, 2}, {3, 4}, {5, 6}}}},
+				expr{"name = $3 AND (tenant_id, id) IN (($4,$5),($6,$7),($8,$9))", []interface{}{"name", int64(1), int64(2), int64(3), int64(4), int64(5), int64(6)}},
+				"",
+				10,
+			},
+			{
+				1,
+				expr{"(tenant_id, id) IN ($1)", []interface{}{Tuples([]*key{{1, 2}, {3, 4}}, "tenant_id", "id")}},
+				expr{"(tenant_id, id) IN (($1,$2),($3,$4))", []interface{}{int64(1), int64(2), int64(3), int64(4)}},
+				"",
+				5,
+			},
+			{
+				1,
+				expr{"hash IN ($1) AND a = $2", []interface{}{[][]byte{[]byte("a"), []byte("b")}, nil}},
+				expr{"hash IN ($1,$2) AND a = $3", []interface{}{[]byte("a"), []byte("b"), nil}},
+				"",
+				4,
+			},
+			{
+				1,
+				expr{"id IN ($1)", []interface{}{[][16]byte{{1}, {2}}}},
+				expr{"id IN ($1,$2)", []interface{}{[16]byte{1}, [16]byte{2}}},
+				"",
+				3,
+			},
+			{
+				1,
+				expr{"doc IN ($1) AND doc = $2", []interface{}{[]json.RawMessage{json.RawMessage(`{}`), json.RawMessage(`[]`)}, json.RawMessage(`{}`)}},
+				expr{"doc IN ($1,$2) AND doc = $3", []interface{}{json.RawMessage(`{}`), json.RawMessage(`[]`), json.RawMessage(`{}`)}},
+				"",
+				4,
+			},
+			{
+				1,
+				expr{"tags IN ($1)", []interface{}{[]pq.StringArray{{"a", "b"}, {"c"}}}},
+				expr{"tags IN ($1,$2)", []interface{}{pq.StringArray{"a", "b"}, pq.StringArray{"c"}}},
+				"",
+				3,
+			},
+			{
+				1,
+				expr{"(a, b) IN ($1)", []interface{}{[][]int{{1, 2}, {3}}}},
+				expr{},
+				"invalid tuple length, expected 2, got 1",
+				0,
+			},
+			{
+				1,
+				expr{"(a, b) IN ($1)", []interface{}{Tuples([]key{{1, 2}}, "tenant_id", "name")}},
+				expr{},
+				"missing column name in builder.key",
+				0,
+			},
+		}
+
+		for i, x := range examples {
+			newIdx, err := x.cond.build(x.startIdx)
+			if x.expectedError == "" {
+				if err != nil {
+					t.Fatalf("example %d: expected error to be nil, got %#v", i, err)
+				}
+				if x.cond.text != x.expected.text {
+					t.Errorf("example %d: expected text to be %q, got %q", i, x.expected.text, x.cond.text)
+				}
+				if !reflect.DeepEqual(x.cond.params, x.expected.params) {
+					t.Errorf("example %d: expected params to be %v, got %v", i, x.expected.params, x.cond.params)
+				}
+				if newIdx != x.expectedNewIdx {
+					t.Errorf("example %d: expected newIdx to be %d, got %d", i, x.expectedNewIdx, newIdx)
+				}
+			} else if err == nil || x.expectedError != err.Error() {
+				t.Fatalf("example %d: expected error to be %q, got %v", i, x.expectedError, err)
+			}
+		}
+	})
 }
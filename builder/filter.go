@@ -0,0 +1,395 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType is a type of filter field values. Values are parsed from their text
+// representation according to the type before being passed as parameters.
+type FieldType int
+
+const (
+	FieldString FieldType = iota
+	FieldInt
+	FieldFloat
+	FieldBool
+	FieldTime // RFC 3339 or 2006-01-02
+)
+
+// FilterOp is a filter operator.
+type FilterOp string
+
+const (
+	OpEq     FilterOp = "eq"
+	OpNe     FilterOp = "ne"
+	OpLt     FilterOp = "lt"
+	OpGt     FilterOp = "gt"
+	OpIn     FilterOp = "in"
+	OpLike   FilterOp = "like"
+	OpIsNull FilterOp = "is_null"
+)
+
+// FilterField describes a field which can be used in filters.
+type FilterField struct {
+	// Column is a column or expression the field is mapped to. It is written to SQL
+	// as-is and must not come from user input.
+	Column string
+	Type   FieldType
+	// Ops lists allowed operators, only OpEq is allowed if empty.
+	Ops []FilterOp
+	// Sortable allows sorting by the field.
+	Sortable bool
+}
+
+// FilterSpec declares fields allowed in filters of a list endpoint. Names "sort",
+// "limit" and "offset" are reserved for sorting and pagination.
+type FilterSpec struct {
+	Fields map[string]FilterField
+	// DefaultSort is used if sort is not specified, see SafeOrderBy for its format.
+	DefaultSort string
+	// DefaultLimit is used if limit is not specified.
+	DefaultLimit uint64
+	// MaxLimit caps requested limit, zero means no cap.
+	MaxLimit uint64
+}
+
+// FilterError is a validation error of a single filter field.
+type FilterError struct {
+	Field   string
+	Op      FilterOp
+	Message string
+}
+
+func (e *FilterError) Error() string {
+	if e.Op != "" {
+		return fmt.Sprintf("%s[%s]: %s", e.Field, e.Op, e.Message)
+	}
+	return e.Field + ": " + e.Message
+}
+
+// FilterErrors is returned by FilterSpec parse methods when the filter is invalid.
+type FilterErrors []*FilterError
+
+func (e FilterErrors) Error() string {
+	ss := make([]string, len(e))
+	for i, fe := range e {
+		ss[i] = fe.Error()
+	}
+	return strings.Join(ss, "; ")
+}
+
+// Filter is a validated filter which can be applied to Selecter.
+type Filter struct {
+	where   exprs
+	orderBy string
+	limit   uint64
+	offset  uint64
+}
+
+// Apply adds filter conditions, sorting and pagination to b.
+func (f *Filter) Apply(b Selecter) Selecter {
+	for _, w := range f.where {
+		b = b.Where(w.text, w.params...)
+	}
+	if f.orderBy != "" {
+		b = b.OrderBy(f.orderBy)
+	}
+	if f.limit > 0 {
+		b = b.Limit(f.limit)
+	}
+	if f.offset > 0 {
+		b = b.Offset(f.offset)
+	}
+	return b
+}
+
+type filterTerm struct {
+	field  string
+	op     FilterOp
+	values []string
+}
+
+// ParseValues parses filter from query string values such as
+// "name=John&age[gt]=18&status[in]=new,open&deleted_at[is_null]=true&sort=-age&limit=10".
+// Values of "in" may be comma separated or repeated.
+func (s *FilterSpec) ParseValues(v url.Values) (*Filter, error) {
+	var terms []*filterTerm
+	var sortBy, limit, offset string
+	var errs FilterErrors
+
+	for key, values := range v {
+		switch key {
+		case "sort":
+			sortBy = strings.Join(values, ",")
+			continue
+		case "limit":
+			limit = values[len(values)-1]
+			continue
+		case "offset":
+			offset = values[len(values)-1]
+			continue
+		}
+
+		t := &filterTerm{field: key, op: OpEq, values: values}
+		if i := strings.IndexByte(key, '['); i > 0 && strings.HasSuffix(key, "]") {
+			t.field, t.op = key[:i], FilterOp(key[i+1:len(key)-1])
+		}
+		if t.op == OpIn {
+			var vv []string
+			for _, v := range values {
+				vv = append(vv, strings.Split(v, ",")...)
+			}
+			t.values = vv
+		} else if len(values) > 1 {
+			errs = append(errs, &FilterError{t.field, t.op, "multiple values"})
+			continue
+		}
+		terms = append(terms, t)
+	}
+	return s.parse(terms, sortBy, limit, offset, errs)
+}
+
+// ParseJSON parses filter from a JSON object such as
+// {"name": "John", "age": {"gt": 18}, "status": {"in": ["new", "open"]}, "sort": "-age", "limit": 10}.
+func (s *FilterSpec) ParseJSON(data []byte) (*Filter, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var obj map[string]interface{}
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	var terms []*filterTerm
+	var sortBy, limit, offset string
+	var errs FilterErrors
+
+	for key, value := range obj {
+		switch key {
+		case "sort", "limit", "offset":
+			str, ok := jsonText(value)
+			if !ok {
+				errs = append(errs, &FilterError{key, "", "invalid value"})
+				continue
+			}
+			switch key {
+			case "sort":
+				sortBy = str
+			case "limit":
+				limit = str
+			case "offset":
+				offset = str
+			}
+			continue
+		}
+
+		ops, ok := value.(map[string]interface{})
+		if !ok {
+			ops = map[string]interface{}{string(OpEq): value}
+		}
+		for op, v := range ops {
+			t := &filterTerm{field: key, op: FilterOp(op)}
+			vv, ok := v.([]interface{})
+			if !ok {
+				vv = []interface{}{v}
+			} else if t.op != OpIn {
+				errs = append(errs, &FilterError{t.field, t.op, "multiple values"})
+				continue
+			}
+			for _, v := range vv {
+				str, ok := jsonText(v)
+				if !ok {
+					errs = append(errs, &FilterError{t.field, t.op, "invalid value"})
+					t = nil
+					break
+				}
+				t.values = append(t.values, str)
+			}
+			if t != nil {
+				terms = append(terms, t)
+			}
+		}
+	}
+	return s.parse(terms, sortBy, limit, offset, errs)
+}
+
+// jsonText returns text representation of a scalar JSON value.
+func jsonText(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+func (s *FilterSpec) parse(terms []*filterTerm, sortBy, limit, offset string, errs FilterErrors) (*Filter, error) {
+	f := &Filter{limit: s.DefaultLimit}
+
+	// conditions are generated in a stable order
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].field != terms[j].field {
+			return terms[i].field < terms[j].field
+		}
+		return terms[i].op < terms[j].op
+	})
+
+	for _, t := range terms {
+		w, err := s.condition(t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		f.where = append(f.where, w)
+	}
+
+	// sort
+	if sortBy == "" {
+		sortBy = s.DefaultSort
+	}
+	allowed := make(map[string]string)
+	for name, field := range s.Fields {
+		if field.Sortable {
+			allowed[name] = field.Column
+		}
+	}
+	orderBy, err := SafeOrderBy(sortBy, allowed)
+	if err != nil {
+		errs = append(errs, &FilterError{"sort", "", err.Error()})
+	}
+	f.orderBy = orderBy
+
+	// limit and offset
+	if limit != "" {
+		n, err := strconv.ParseUint(limit, 10, 64)
+		if err != nil {
+			errs = append(errs, &FilterError{"limit", "", "invalid integer value"})
+		} else if n > 0 {
+			f.limit = n
+		}
+	}
+	if s.MaxLimit > 0 && (f.limit == 0 || f.limit > s.MaxLimit) {
+		f.limit = s.MaxLimit
+	}
+	if offset != "" {
+		n, err := strconv.ParseUint(offset, 10, 64)
+		if err != nil {
+			errs = append(errs, &FilterError{"offset", "", "invalid integer value"})
+		}
+		f.offset = n
+	}
+
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			if errs[i].Field != errs[j].Field {
+				return errs[i].Field < errs[j].Field
+			}
+			return errs[i].Op < errs[j].Op
+		})
+		return nil, errs
+	}
+	return f, nil
+}
+
+// condition returns WHERE condition for the term.
+func (s *FilterSpec) condition(t *filterTerm) (*expr, *FilterError) {
+	field, ok := s.Fields[t.field]
+	if !ok {
+		return nil, &FilterError{t.field, "", "unknown field"}
+	}
+	if !field.allows(t.op) {
+		return nil, &FilterError{t.field, t.op, "operator not allowed"}
+	}
+
+	if len(t.values) == 0 {
+		return nil, &FilterError{t.field, t.op, "missing value"}
+	}
+
+	if t.op == OpIsNull {
+		isNull, err := strconv.ParseBool(t.values[0])
+		if err != nil {
+			return nil, &FilterError{t.field, t.op, "invalid boolean value"}
+		}
+		if isNull {
+			return &expr{field.Column + " IS NULL", nil}, nil
+		}
+		return &expr{field.Column + " IS NOT NULL", nil}, nil
+	}
+
+	params := make([]interface{}, len(t.values))
+	for i, v := range t.values {
+		p, err := field.Type.parse(strings.TrimSpace(v))
+		if err != nil {
+			return nil, &FilterError{t.field, t.op, err.Error()}
+		}
+		params[i] = p
+	}
+
+	switch t.op {
+	case OpEq:
+		return &expr{field.Column + " = $1", params}, nil
+	case OpNe:
+		return &expr{field.Column + " <> $1", params}, nil
+	case OpLt:
+		return &expr{field.Column + " < $1", params}, nil
+	case OpGt:
+		return &expr{field.Column + " > $1", params}, nil
+	case OpLike:
+		return &expr{field.Column + " LIKE $1", params}, nil
+	case OpIn:
+		return &expr{field.Column + " IN ($1)", []interface{}{params}}, nil
+	}
+	return nil, &FilterError{t.field, t.op, "unknown operator"}
+}
+
+func (f FilterField) allows(op FilterOp) bool {
+	if len(f.Ops) == 0 {
+		return op == OpEq
+	}
+	for _, o := range f.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parse converts text to a parameter value of the type.
+func (t FieldType) parse(s string) (interface{}, error) {
+	switch t {
+	case FieldInt:
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n, nil
+		}
+		return nil, errors.New("invalid integer value")
+	case FieldFloat:
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n, nil
+		}
+		return nil, errors.New("invalid number value")
+	case FieldBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+		return nil, errors.New("invalid boolean value")
+	case FieldTime:
+		if tm, err := time.Parse(time.RFC3339, s); err == nil {
+			return tm, nil
+		}
+		if tm, err := time.Parse("2006-01-02", s); err == nil {
+			return tm, nil
+		}
+		return nil, errors.New("invalid time value")
+	}
+	return s, nil
+}
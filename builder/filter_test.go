@@ -0,0 +1,147 @@
+package builder
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testFilterSpec = &FilterSpec{
+	Fields: map[string]FilterField{
+		"name":       {Column: "u.name", Ops: []FilterOp{OpEq, OpLike}, Sortable: true},
+		"age":        {Column: "u.age", Type: FieldInt, Ops: []FilterOp{OpEq, OpNe, OpLt, OpGt}, Sortable: true},
+		"status":     {Column: "u.status", Ops: []FilterOp{OpIn}},
+		"active":     {Column: "u.active", Type: FieldBool},
+		"created":    {Column: "u.created_at", Type: FieldTime, Ops: []FilterOp{OpGt}, Sortable: true},
+		"deleted_at": {Column: "u.deleted_at", Type: FieldTime, Ops: []FilterOp{OpIsNull}},
+	},
+	DefaultSort:  "-created",
+	DefaultLimit: 20,
+	MaxLimit:     100,
+}
+
+func TestFilterParseValues(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		v, _ := url.ParseQuery("name=John&age[gt]=18&age[lt]=65&status[in]=new,open&status[in]=closed&deleted_at[is_null]=true&sort=name,-age&limit=10&offset=30")
+		expectedSql := "SELECT * FROM users u WHERE (u.age > $1) AND (u.age < $2) AND (u.deleted_at IS NULL) AND (u.name = $3) AND (u.status IN ($4,$5,$6)) ORDER BY u.name, u.age DESC OFFSET 30 LIMIT 10"
+
+		f, err := testFilterSpec.ParseValues(v)
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		sql, params, err := f.Apply(Select("*").From("users u")).Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 6); err != nil {
+			t.Error(err)
+		}
+		expectedParams := []interface{}{int64(18), int64(65), "John", "new", "open", "closed"}
+		if !reflect.DeepEqual(params, expectedParams) {
+			t.Errorf("expected params %v, got %v", expectedParams, params)
+		}
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		expectedSql := "SELECT * FROM users u ORDER BY u.created_at DESC LIMIT 20"
+
+		f, err := testFilterSpec.ParseValues(url.Values{})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		sql, params, err := f.Apply(Select("*").From("users u")).Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 0); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("LimitCap", func(t *testing.T) {
+		f, err := testFilterSpec.ParseValues(url.Values{"limit": {"1000"}})
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+		if f.limit != 100 {
+			t.Errorf("expected limit to be 100, got %d", f.limit)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		v, _ := url.ParseQuery("email=x&age[like]=1&age[gt]=abc&name=a&name=b&deleted_at[is_null]=maybe&sort=email&limit=-1")
+		_, err := testFilterSpec.ParseValues(v)
+
+		var errs FilterErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("expected FilterErrors, got %v", err)
+		}
+		expected := FilterErrors{
+			{"age", OpGt, "invalid integer value"},
+			{"age", OpLike, "operator not allowed"},
+			{"deleted_at", OpIsNull, "invalid boolean value"},
+			{"email", "", "unknown field"},
+			{"limit", "", "invalid integer value"},
+			{"name", OpEq, "multiple values"},
+			{"sort", "", `invalid sort field: "email"`},
+		}
+		if !reflect.DeepEqual(errs, expected) {
+			t.Errorf("expected errors %v, got %v", expected, errs)
+		}
+	})
+}
+
+func TestFilterParseJSON(t *testing.T) {
+	t.Run("Simple", func(t *testing.T) {
+		data := `{"active": true, "created": {"gt": "2020-01-02"}, "status": {"in": ["new", "open"]}, "sort": "age", "limit": 5}`
+		expectedSql := "SELECT * FROM users u WHERE (u.active = $1) AND (u.created_at > $2) AND (u.status IN ($3,$4)) ORDER BY u.age LIMIT 5"
+
+		f, err := testFilterSpec.ParseJSON([]byte(data))
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		sql, params, err := f.Apply(Select("*").From("users u")).Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 4); err != nil {
+			t.Error(err)
+		}
+		expectedParams := []interface{}{true, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), "new", "open"}
+		if !reflect.DeepEqual(params, expectedParams) {
+			t.Errorf("expected params %v, got %v", expectedParams, params)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		data := `{"age": [1, 2], "name": {"like": {"x": 1}}, "status": {"in": []}}`
+		_, err := testFilterSpec.ParseJSON([]byte(data))
+
+		var errs FilterErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("expected FilterErrors, got %v", err)
+		}
+		expected := FilterErrors{
+			{"age", OpEq, "multiple values"},
+			{"name", OpLike, "invalid value"},
+			{"status", OpIn, "missing value"},
+		}
+		if !reflect.DeepEqual(errs, expected) {
+			t.Errorf("expected errors %v, got %v", expected, errs)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		if _, err := testFilterSpec.ParseJSON([]byte(`{`)); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
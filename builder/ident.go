@@ -0,0 +1,83 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ident returns a quoted identifier built from parts, e.g. Ident("public", "users")
+// returns "public"."users". Quoted identifiers can be used anywhere a table or column
+// name is accepted. Double quotes in parts are escaped as required by PostgreSQL.
+// Statements built for MySQL have quoted identifiers converted to backticks.
+func Ident(parts ...string) string {
+	var sb strings.Builder
+	for i, p := range parts {
+		if i > 0 {
+			sb.WriteRune('.')
+		}
+		sb.WriteRune('"')
+		sb.WriteString(strings.ReplaceAll(p, `"`, `""`))
+		sb.WriteRune('"')
+	}
+	return sb.String()
+}
+
+// SafeOrderBy converts untrusted input, such as an HTTP query parameter, to an ORDER BY
+// list which can be passed to Selecter.OrderBy. Input is a comma separated list of field
+// names, each optionally prefixed with "-" (descending) or "+" (ascending), or followed
+// by ASC/DESC and NULLS FIRST/LAST, e.g. "-created_at,name asc nulls last". Fields are
+// mapped to column expressions using allowed; unknown fields result in an error.
+func SafeOrderBy(input string, allowed map[string]string) (string, error) {
+	if isBlank(input) {
+		return "", nil
+	}
+
+	var res []string
+	for _, item := range strings.Split(input, ",") {
+		words := strings.Fields(item)
+		if len(words) == 0 {
+			return "", fmt.Errorf("invalid sort expression: %q", input)
+		}
+
+		field := words[0]
+		dir := ""
+		switch field[0] {
+		case '-':
+			field, dir = field[1:], "DESC"
+		case '+':
+			field, dir = field[1:], "ASC"
+		}
+		col, ok := allowed[field]
+		if !ok || isBlank(col) {
+			return "", fmt.Errorf("invalid sort field: %q", field)
+		}
+
+		nulls := ""
+		for i := 1; i < len(words); i++ {
+			switch w := strings.ToUpper(words[i]); {
+			case (w == "ASC" || w == "DESC") && dir == "" && nulls == "":
+				dir = w
+			case w == "NULLS" && nulls == "" && i+1 < len(words):
+				i++
+				switch n := strings.ToUpper(words[i]); n {
+				case "FIRST", "LAST":
+					nulls = "NULLS " + n
+				default:
+					return "", fmt.Errorf("invalid sort expression: %q", strings.TrimSpace(item))
+				}
+			default:
+				return "", fmt.Errorf("invalid sort expression: %q", strings.TrimSpace(item))
+			}
+		}
+
+		s := col
+		if dir != "" {
+			s += " " + dir
+		}
+		if nulls != "" {
+			s += " " + nulls
+		}
+		res = append(res, s)
+	}
+	return strings.Join(res, ", "), nil
+}
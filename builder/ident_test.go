@@ -0,0 +1,92 @@
+package builder
+
+import "testing"
+
+func TestIdent(t *testing.T) {
+	examples := []struct {
+		parts    []string
+		expected string
+	}{
+		{[]string{"users"}, `"users"`},
+		{[]string{"public", "Users"}, `"public"."Users"`},
+		{[]string{`users"; DROP TABLE users; --`}, `"users""; DROP TABLE users; --"`},
+	}
+	for i, x := range examples {
+		if s := Ident(x.parts...); s != x.expected {
+			t.Errorf("example %d: expected %q, got %q", i, x.expected, s)
+		}
+	}
+
+	t.Run("Builder", func(t *testing.T) {
+		expectedSql := `SELECT "first name" FROM "public"."users" ORDER BY "first name" DESC`
+		b := Select(Ident("first name")).
+			From(Ident("public", "users")).
+			OrderBy(Ident("first name") + " DESC")
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 0); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("SpecialCharacters", func(t *testing.T) {
+		expectedSql := `SELECT "it's", "$1", "a\" FROM "we're"."$2\" WHERE (id = $1) ORDER BY "it's", "a\""" DESC`
+		b := Select(Ident("it's"), Ident("$1"), Ident(`a\`)).
+			From(Ident("we're", `$2\`)).
+			Where("id = $1", 1).
+			OrderBy(Ident("it's") + ", " + Ident(`a\"`) + " DESC")
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 1); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestSafeOrderBy(t *testing.T) {
+	allowed := map[string]string{
+		"name":    "users.last_name",
+		"created": "users.created_at",
+		"id":      "users.id",
+	}
+
+	examples := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{"", "", ""},
+		{"name", "users.last_name", ""},
+		{"-created,+id", "users.created_at DESC, users.id ASC", ""},
+		{"name desc nulls last, id", "users.last_name DESC NULLS LAST, users.id", ""},
+		{"-created NULLS first", "users.created_at DESC NULLS FIRST", ""},
+		{"email", "", `invalid sort field: "email"`},
+		{"name; DROP TABLE users", "", `invalid sort field: "name;"`},
+		{"name desc asc", "", `invalid sort expression: "name desc asc"`},
+		{"name nulls", "", `invalid sort expression: "name nulls"`},
+		{"name,", "", `invalid sort expression: "name,"`},
+	}
+	for i, x := range examples {
+		s, err := SafeOrderBy(x.input, allowed)
+		if x.err != "" {
+			if err == nil || err.Error() != x.err {
+				t.Errorf("example %d: expected error %q, got %v", i, x.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("example %d: expected err to be nil, got %v", i, err)
+		}
+		if s != x.expected {
+			t.Errorf("example %d: expected %q, got %q", i, x.expected, s)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package builder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Kind is a kind of SQL statement.
+type Kind int
+
+const (
+	// KindRaw is a statement which could not be classified.
+	KindRaw Kind = iota
+	KindSelect
+	KindInsert
+	KindUpdate
+	KindDelete
+	KindMerge
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindRaw:
+		return "RAW"
+	case KindSelect:
+		return "SELECT"
+	case KindInsert:
+		return "INSERT"
+	case KindUpdate:
+		return "UPDATE"
+	case KindDelete:
+		return "DELETE"
+	case KindMerge:
+		return "MERGE"
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Info describes a statement generated by a builder.
+type Info struct {
+	Kind Kind
+	// Table is the target table of INSERT, UPDATE, DELETE and MERGE statements.
+	Table string
+	// Tables lists tables referenced in FROM, USING, JOIN and WITH queries, excluding
+	// Table and WITH query names.
+	Tables []string
+	// Returning is true if the statement has a RETURNING clause.
+	Returning bool
+	// ModifyingWith is true if the statement has data-modifying WITH queries.
+	ModifyingWith bool
+	// Locking is true if the statement or its subqueries lock rows with FOR UPDATE,
+	// FOR NO KEY UPDATE, FOR SHARE or FOR KEY SHARE.
+	Locking bool
+	// Multiple is true if the query has several statements separated by semicolons.
+	// Other fields describe the first statement only.
+	Multiple bool
+}
+
+// ReadOnly returns true if the statement does not modify data or lock rows, e.g. so
+// that it can be run on a replica or in a read-only transaction.
+func (i Info) ReadOnly() bool {
+	return i.Kind == KindSelect && !i.ModifyingWith && !i.Locking && !i.Multiple
+}
+
+// Describer is implemented by builders which can describe their statements without
+// building them.
+type Describer interface {
+	Describe() Info
+}
+
+// Describe returns Info for the statement generated by b. Builders which do not
+// implement Describer are built and classified with Classify.
+func Describe(b Builder) Info {
+	if d, ok := b.(Describer); ok {
+		return d.Describe()
+	}
+	sql, _, err := b.Build()
+	if err != nil {
+		return Info{Kind: KindRaw}
+	}
+	return Classify(sql)
+}
+
+// Classify returns Info for the query using a lightweight scan of its leading keywords.
+// Only Kind, Table, Returning, ModifyingWith, Locking and Multiple are set.
+func Classify(query string) Info {
+	var info Info
+	var inWith, inParen bool
+
+	tt := tokenize(query)
+	for i, t := range tt {
+		if t.text == ";" && t.depth == 0 && i+1 < len(tt) && tt[i+1].text != ";" {
+			info.Multiple = true
+			break
+		}
+	}
+	for i, t := range tt {
+		if t.text == ";" && t.depth == 0 {
+			break
+		}
+		up := strings.ToUpper(t.text)
+		if i > 0 && tt[i-1].text == "(" && isModifying(up) {
+			info.ModifyingWith = true
+		}
+		if isLocking(tt, i) {
+			info.Locking = true
+		}
+		if info.Kind != KindRaw {
+			if t.depth == 0 && up == "RETURNING" {
+				info.Returning = true
+			}
+			continue
+		}
+		if t.depth > 0 && !inParen {
+			continue
+		}
+
+		switch up {
+		case "SELECT", "VALUES", "TABLE":
+			info.Kind = KindSelect
+		case "INSERT":
+			info.Kind = KindInsert
+			info.Table = nextWord(tt, i+1, "INTO")
+		case "MERGE":
+			info.Kind = KindMerge
+			info.Table = nextWord(tt, i+1, "INTO")
+		case "UPDATE":
+			info.Kind = KindUpdate
+			info.Table = nextWord(tt, i+1, "ONLY")
+		case "DELETE":
+			info.Kind = KindDelete
+			info.Table = nextWord(tt, i+1, "FROM", "ONLY")
+		case "WITH":
+			inWith = true
+		case "(":
+			if i == 0 {
+				inParen = true // parenthesized query
+			}
+		default:
+			if !inWith && !inParen {
+				return info // unknown leading keyword
+			}
+		}
+	}
+	return info
+}
+
+func isModifying(keyword string) bool {
+	switch keyword {
+	case "INSERT", "UPDATE", "DELETE", "MERGE":
+		return true
+	}
+	return false
+}
+
+// isLocking returns true if the token at idx starts a locking clause.
+func isLocking(tt []token, idx int) bool {
+	if !strings.EqualFold(tt[idx].text, "FOR") || idx+1 == len(tt) {
+		return false
+	}
+	switch strings.ToUpper(tt[idx+1].text) {
+	case "UPDATE", "SHARE", "NO", "KEY":
+		return true
+	}
+	return false
+}
+
+// hasLocking returns true if any of texts has a locking clause, e.g. in a subquery.
+func hasLocking(texts ...string) bool {
+	for _, text := range texts {
+		tt := tokenize(text)
+		for i := range tt {
+			if isLocking(tt, i) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextWord returns the first token starting from idx which is not one of skip keywords.
+func nextWord(tt []token, idx int, skip ...string) string {
+	for ; idx < len(tt); idx++ {
+		up := strings.ToUpper(tt[idx].text)
+		skipped := false
+		for _, s := range skip {
+			if up == s {
+				skipped = true
+				break
+			}
+		}
+		if !skipped {
+			if isWordToken(tt[idx].text) {
+				return tt[idx].text
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// tablesIn returns table names referenced in FROM-like list text: the first item,
+// items after commas and items after JOIN. Subqueries and function calls are skipped.
+func tablesIn(text string) []string {
+	var res []string
+	expect := true
+	tt := tokenize(text)
+	for i, t := range tt {
+		if t.depth > 0 {
+			continue
+		}
+		switch up := strings.ToUpper(t.text); {
+		case t.text == "(":
+			expect = false
+		case t.text == "," || up == "JOIN":
+			expect = true
+		case up == "ONLY" || up == "LATERAL":
+		case expect && isWordToken(t.text):
+			expect = false
+			if i+1 < len(tt) && tt[i+1].text == "(" {
+				continue // function call
+			}
+			res = append(res, t.text)
+		}
+	}
+	return res
+}
+
+type token struct {
+	text  string
+	depth int
+}
+
+// tokenize splits s into words and punctuation, skipping whitespace, comments and
+// string literals. Each token records its parentheses depth; parentheses themselves
+// have the depth of the enclosing text.
+func tokenize(s string) []token {
+	var res []token
+	rr := []rune(s)
+	depth := 0
+	for i := 0; i < len(rr); {
+		r := rr[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case r == '-' && i+1 < len(rr) && rr[i+1] == '-':
+			for i < len(rr) && rr[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(rr) && rr[i+1] == '*':
+			i += 2
+			for i < len(rr) && !(rr[i] == '*' && i+1 < len(rr) && rr[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '\'':
+			i++
+			for i < len(rr) {
+				if rr[i] == '\'' {
+					if i+1 < len(rr) && rr[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			i++
+			res = append(res, token{"''", depth})
+		case r == '(':
+			res = append(res, token{"(", depth})
+			depth++
+			i++
+		case r == ')':
+			if depth > 0 {
+				depth--
+			}
+			res = append(res, token{")", depth})
+			i++
+		case isWordRune(r) || r == '"':
+			start := i
+			for i < len(rr) && (isWordRune(rr[i]) || rr[i] == '"') {
+				if rr[i] == '"' {
+					i++
+					for i < len(rr) && rr[i] != '"' {
+						i++
+					}
+				}
+				i++
+			}
+			if i > len(rr) {
+				i = len(rr)
+			}
+			res = append(res, token{string(rr[start:i]), depth})
+		default:
+			res = append(res, token{string(r), depth})
+			i++
+		}
+	}
+	return res
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || r == '$' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127
+}
+
+func isWordToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	r := []rune(s)[0]
+	return r == '"' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127
+}
+
+// infoCollector accumulates referenced tables excluding WITH query names.
+type infoCollector struct {
+	info  Info
+	ctes  map[string]bool
+	seen  map[string]bool
+	names []string
+}
+
+func (c *infoCollector) withs(ww withs) {
+	for _, w := range ww {
+		if c.ctes == nil {
+			c.ctes = map[string]bool{}
+		}
+		c.ctes[w.name] = true
+		wi := Describe(w.query)
+		if wi.Kind != KindSelect && wi.Kind != KindRaw || wi.ModifyingWith {
+			c.info.ModifyingWith = true
+		}
+		if wi.Locking {
+			c.info.Locking = true
+		}
+		if wi.Table != "" {
+			c.add(wi.Table)
+		}
+		c.add(wi.Tables...)
+	}
+}
+
+func (c *infoCollector) query(b Builder) {
+	qi := Describe(b)
+	if qi.ModifyingWith {
+		c.info.ModifyingWith = true
+	}
+	if qi.Locking {
+		c.info.Locking = true
+	}
+	c.add(qi.Tables...)
+}
+
+// from adds tables referenced in FROM-like list, which builders join with spaces.
+func (c *infoCollector) from(ss []string) {
+	text := strings.Join(ss, " ")
+	if hasLocking(text) {
+		c.info.Locking = true // locking subquery
+	}
+	c.add(tablesIn(text)...)
+}
+
+func (c *infoCollector) add(tables ...string) {
+	for _, t := range tables {
+		if c.seen == nil {
+			c.seen = map[string]bool{}
+		}
+		if c.seen[t] {
+			continue
+		}
+		c.seen[t] = true
+		c.names = append(c.names, t)
+	}
+}
+
+func (c *infoCollector) result() Info {
+	for _, t := range c.names {
+		if !c.ctes[t] && t != c.info.Table {
+			c.info.Tables = append(c.info.Tables, t)
+		}
+	}
+	return c.info
+}
+
+func (b *selecter) Describe() Info {
+	c := &infoCollector{info: Info{Kind: KindSelect}}
+	texts := append(append(b.columns.texts(), b.where.texts()...), b.having.texts()...)
+	c.info.Locking = b.locking != "" || hasLocking(texts...)
+	c.withs(b.with)
+	c.from(b.from.texts())
+	for _, u := range b.union {
+		c.query(u.query)
+	}
+	return c.result()
+}
+
+func (b *inserter) Describe() Info {
+	c := &infoCollector{info: Info{Kind: KindInsert, Table: b.into, Returning: len(b.returning) > 0}}
+	c.withs(b.with)
+	if b.from != nil {
+		c.query(b.from)
+	}
+	return c.result()
+}
+
+func (b *upserter) Describe() Info {
+	c := &infoCollector{info: Info{Kind: KindInsert, Table: b.into, Returning: len(b.returning) > 0}}
+	c.withs(b.with)
+	if b.from != nil {
+		c.query(b.from)
+	}
+	return c.result()
+}
+
+func (b *insecter) Describe() Info {
+	// insect is implemented with a data-modifying WITH query and always returns rows
+	return Info{Kind: KindInsert, Table: b.table, Returning: true, ModifyingWith: true}
+}
+
+func (b *updater) Describe() Info {
+	c := &infoCollector{info: Info{Kind: KindUpdate, Table: b.table, Returning: len(b.returning) > 0}}
+	c.withs(b.with)
+	c.from(b.from.texts())
+	return c.result()
+}
+
+func (b *deleter) Describe() Info {
+	c := &infoCollector{info: Info{Kind: KindDelete, Table: b.from, Returning: len(b.returning) > 0}}
+	c.withs(b.with)
+	c.from(b.using)
+	return c.result()
+}
+
+func (b *sqler) Describe() Info {
+	return Classify(b.query.text)
+}
@@ -0,0 +1,140 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	examples := []struct {
+		b        Builder
+		expected Info
+	}{
+		{
+			Select("*").From("users"),
+			Info{Kind: KindSelect, Tables: []string{"users"}},
+		},
+		{
+			Select("users.first_name").
+				With("u2", Select("first_name").From("people p, public.accounts a")).
+				From("users").
+				From("INNER JOIN u2 ON u2.first_name = users.first_name").
+				From("LEFT JOIN LATERAL (SELECT * FROM orders) o ON true").
+				From("CROSS JOIN generate_series(1, 10) g").
+				Union(true, Select("name").From(`"Archive"`)),
+			Info{Kind: KindSelect, Tables: []string{"people", "public.accounts", "users", `"Archive"`}},
+		},
+		{
+			Select("*").From("ins").With("ins", Insert("users").Values(1).Returning("*")),
+			Info{Kind: KindSelect, Tables: []string{"users"}, ModifyingWith: true},
+		},
+		{
+			Select("*").From("users").For("UPDATE"),
+			Info{Kind: KindSelect, Tables: []string{"users"}, Locking: true},
+		},
+		{
+			Select("*").From("users").Where("id IN (SELECT id FROM accounts FOR SHARE)"),
+			Info{Kind: KindSelect, Tables: []string{"users"}, Locking: true},
+		},
+		{
+			Select("id", "(SELECT name FROM accounts WHERE id = 1 FOR UPDATE)").From("users"),
+			Info{Kind: KindSelect, Tables: []string{"users"}, Locking: true},
+		},
+		{
+			Select("role", "count(*)").From("users").GroupBy("role").Having("count(*) > (SELECT count(*) FROM accounts FOR SHARE)"),
+			Info{Kind: KindSelect, Tables: []string{"users"}, Locking: true},
+		},
+		{
+			Select("*").From("u").With("u", Select("*").From("users").For("NO KEY UPDATE")),
+			Info{Kind: KindSelect, Tables: []string{"users"}, Locking: true},
+		},
+		{
+			Insert("users").From(Select("*").From("people")).Returning("id"),
+			Info{Kind: KindInsert, Table: "users", Tables: []string{"people"}, Returning: true},
+		},
+		{
+			Upsert("users", "(email)").Columns("email").Values("a"),
+			Info{Kind: KindInsert, Table: "users"},
+		},
+		{
+			Insect("users").Columns("email").Values("a"),
+			Info{Kind: KindInsert, Table: "users", Returning: true, ModifyingWith: true},
+		},
+		{
+			Update("users").Set("a = 1").From("accounts a JOIN people p ON p.id = a.id"),
+			Info{Kind: KindUpdate, Table: "users", Tables: []string{"accounts", "people"}},
+		},
+		{
+			Delete("users").Using("accounts").Returning("*"),
+			Info{Kind: KindDelete, Table: "users", Tables: []string{"accounts"}, Returning: true},
+		},
+		{
+			SQL("INSERT INTO users (a) VALUES ($1) RETURNING id", 1),
+			Info{Kind: KindInsert, Table: "users", Returning: true},
+		},
+	}
+
+	for i, x := range examples {
+		info := Describe(x.b)
+		if !reflect.DeepEqual(info, x.expected) {
+			t.Errorf("example %d: expected %+v, got %+v", i, x.expected, info)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	examples := []struct {
+		query    string
+		expected bool
+	}{
+		{"SELECT * FROM users", true},
+		{"SELECT * FROM users FOR UPDATE", false},
+		{"SELECT * FROM users FOR SHARE", false},
+		{"SELECT 1; DELETE FROM users", false},
+		{"WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", false},
+		{"DELETE FROM users", false},
+		{"CREATE TABLE users (id int)", false},
+	}
+
+	for i, x := range examples {
+		if ro := Classify(x.query).ReadOnly(); ro != x.expected {
+			t.Errorf("example %d: expected %v, got %v", i, x.expected, ro)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	examples := []struct {
+		query    string
+		expected Info
+	}{
+		{"select 1", Info{Kind: KindSelect}},
+		{"  -- comment\n/* block */ SELECT * FROM users", Info{Kind: KindSelect}},
+		{"(SELECT 1) UNION (SELECT 2)", Info{Kind: KindSelect}},
+		{"VALUES (1), (2)", Info{Kind: KindSelect}},
+		{"WITH a AS (SELECT 1), b AS MATERIALIZED (SELECT 2) SELECT * FROM a, b", Info{Kind: KindSelect}},
+		{"WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", Info{Kind: KindSelect, ModifyingWith: true}},
+		{"WITH s AS (SELECT 'DELETE') SELECT * FROM s", Info{Kind: KindSelect}},
+		{"insert into public.users (a) values ($1)", Info{Kind: KindInsert, Table: "public.users"}},
+		{"UPDATE ONLY users SET a = 1 RETURNING a", Info{Kind: KindUpdate, Table: "users", Returning: true}},
+		{"DELETE FROM \"Users\" WHERE a = 'returning'", Info{Kind: KindDelete, Table: `"Users"`}},
+		{"MERGE INTO users u USING people p ON u.id = p.id WHEN MATCHED THEN DO NOTHING", Info{Kind: KindMerge, Table: "users"}},
+		{"SELECT * FROM users FOR UPDATE", Info{Kind: KindSelect, Locking: true}},
+		{"SELECT * FROM users u FOR NO KEY UPDATE OF u NOWAIT", Info{Kind: KindSelect, Locking: true}},
+		{"select * from users for share skip locked", Info{Kind: KindSelect, Locking: true}},
+		{"SELECT * FROM (SELECT * FROM users FOR KEY SHARE) u", Info{Kind: KindSelect, Locking: true}},
+		{"WITH u AS (SELECT * FROM users FOR UPDATE) SELECT * FROM u", Info{Kind: KindSelect, Locking: true}},
+		{"SELECT 'FOR UPDATE' FROM users", Info{Kind: KindSelect}},
+		{"SELECT 1; DELETE FROM users RETURNING *", Info{Kind: KindSelect, Multiple: true}},
+		{"SELECT ';' FROM users; -- ; DELETE", Info{Kind: KindSelect}},
+		{"CREATE TABLE users (id int)", Info{Kind: KindRaw}},
+		{"", Info{Kind: KindRaw}},
+	}
+
+	for i, x := range examples {
+		info := Classify(x.query)
+		if !reflect.DeepEqual(info, x.expected) {
+			t.Errorf("example %d: expected %+v, got %+v", i, x.expected, info)
+		}
+	}
+}
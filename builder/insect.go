@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"strconv"
 )
 
 type insecter struct {
@@ -35,7 +34,7 @@ func (b *insecter) Returning(returning ...string) Insecter {
 	return b
 }
 
-func (b *insecter) buildWith() withs {
+func (b *insecter) buildWith() (withs, error) {
 	res := withs{}
 
 	// select
@@ -54,12 +53,9 @@ func (b *insecter) buildWith() withs {
 		if i > 0 {
 			buf.WriteString(", ")
 		}
-		if _, ok := v.(DefaultValue); ok {
-			buf.WriteString("DEFAULT")
-		} else {
-			vals = append(vals, v)
-			buf.WriteRune('$')
-			buf.WriteString(strconv.Itoa(len(vals)))
+		var err error
+		if vals, err = buildValue(&buf, v, vals); err != nil {
+			return nil, err
 		}
 	}
 
@@ -71,11 +67,19 @@ func (b *insecter) buildWith() withs {
 		Returning(b.returning...)
 
 	res = append(res, &with{"ins", bIns})
-	return res
+	return res, nil
 }
 
 func (b *insecter) Build() (string, []interface{}, error) {
+	return b.BuildDialect(Postgres)
+}
+
+func (b *insecter) BuildDialect(d Dialect) (string, []interface{}, error) {
 	// verify
+	if d != Postgres {
+		return "", nil, d.unsupported("insect") // requires data-modifying WITH queries
+	}
+
 	if isBlank(b.table) {
 		return "", nil, errors.New("empty table")
 	}
@@ -102,7 +106,11 @@ func (b *insecter) Build() (string, []interface{}, error) {
 	var buf bytes.Buffer
 
 	// with
-	if with := b.buildWith(); with != nil && len(with) > 0 {
+	with, err := b.buildWith()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(with) > 0 {
 		sql, pps, err := with.build()
 		if err != nil {
 			return "", nil, err
@@ -119,5 +127,8 @@ func (b *insecter) Build() (string, []interface{}, error) {
 	}
 	buf.WriteString(sql)
 
-	return buf.String(), params, nil
+	if err := checkParams(params); err != nil {
+		return "", nil, err
+	}
+	return d.rebind(buf.String(), params)
 }
@@ -57,4 +57,21 @@ func TestInsect(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("WithExpr", func(t *testing.T) {
+		expectedSql := "WITH sel AS (SELECT * FROM table WHERE (d = $1)), ins AS (INSERT INTO table (a, b, c) SELECT $2, now(), nextval($3) WHERE (NOT EXISTS(SELECT * FROM sel)) RETURNING *) SELECT * FROM ins UNION ALL SELECT * FROM sel"
+		b := Insect("table").
+			Columns("a", "b", "c").
+			Values(1, Expr("now()"), Expr("nextval($1)", "seq")).
+			Where("d = $1", "aaa")
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 3); err != nil {
+			t.Error(err)
+		}
+	})
 }
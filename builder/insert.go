@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"strconv"
 )
 
 type inserter struct {
@@ -12,6 +11,8 @@ type inserter struct {
 	into                string
 	columns             []string
 	values              [][]interface{}
+	unnest              bool
+	unnestTypes         []string
 	from                Selecter
 	onConflictDoNothing bool
 	onConflictTarget    *expr
@@ -33,6 +34,12 @@ func (b *inserter) Values(values ...interface{}) Inserter {
 	return b
 }
 
+func (b *inserter) Unnest(types ...string) Inserter {
+	b.unnest = true
+	b.unnestTypes = types
+	return b
+}
+
 func (b *inserter) From(q Selecter) Inserter {
 	b.from = q
 	return b
@@ -51,7 +58,25 @@ func (b *inserter) Returning(returning ...string) Inserter {
 	return b
 }
 
+func (b *inserter) Chunks(size int) ([]Builder, error) {
+	chunks, err := chunkRows(b.values, size)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Builder, len(chunks))
+	for i, rows := range chunks {
+		c := *b
+		c.values = rows
+		res[i] = &c
+	}
+	return res, nil
+}
+
 func (b *inserter) Build() (string, []interface{}, error) {
+	return b.BuildDialect(Postgres)
+}
+
+func (b *inserter) BuildDialect(d Dialect) (string, []interface{}, error) {
 	// verify
 	if len(b.columns) > 0 && len(b.values) > 0 {
 		for _, row := range b.values {
@@ -65,6 +90,14 @@ func (b *inserter) Build() (string, []interface{}, error) {
 		return "", nil, errors.New("values must be empty if from is specified")
 	}
 
+	if b.unnest && d != Postgres {
+		return "", nil, d.unsupported("unnest")
+	}
+
+	if err := d.returning(b.returning); err != nil {
+		return "", nil, err
+	}
+
 	// build
 	var params []interface{}
 	var buf bytes.Buffer
@@ -100,7 +133,13 @@ func (b *inserter) Build() (string, []interface{}, error) {
 	}
 
 	// values
-	if len(b.values) > 0 {
+	if b.unnest {
+		buf.WriteRune(' ')
+		var err error
+		if params, err = buildUnnest(&buf, b.values, b.unnestTypes, params); err != nil {
+			return "", nil, err
+		}
+	} else if len(b.values) > 0 {
 		buf.WriteString(" VALUES ")
 		for j, row := range b.values {
 			if j > 0 {
@@ -111,12 +150,9 @@ func (b *inserter) Build() (string, []interface{}, error) {
 				if i > 0 {
 					buf.WriteString(", ")
 				}
-				if _, ok := v.(DefaultValue); ok {
-					buf.WriteString("DEFAULT")
-				} else {
-					params = append(params, v)
-					buf.WriteRune('$')
-					buf.WriteString(strconv.Itoa(len(params)))
+				var err error
+				if params, err = buildValue(&buf, v, params); err != nil {
+					return "", nil, err
 				}
 			}
 			buf.WriteRune(')')
@@ -143,18 +179,29 @@ func (b *inserter) Build() (string, []interface{}, error) {
 	}
 
 	// on conflict: do nothing
-	if b.onConflictDoNothing {
+	if b.onConflictDoNothing && d == MySQL {
+		// MySQL has no DO NOTHING, assigning a column to itself leaves the row intact;
+		// conflict target is ignored as any unique key conflict triggers the update
+		if len(b.columns) == 0 {
+			return "", nil, errors.New("columns required for ON DUPLICATE KEY UPDATE")
+		}
+		buf.WriteString(" ON DUPLICATE KEY UPDATE ")
+		buf.WriteString(b.columns[0])
+		buf.WriteString(" = ")
+		buf.WriteString(b.columns[0])
+	} else if b.onConflictDoNothing {
 		buf.WriteString(" ON CONFLICT ")
 
 		if b.onConflictTarget != nil {
 			// validate and rename target condition
-			if _, err := b.onConflictTarget.build(len(params) + 1); err != nil {
+			target := *b.onConflictTarget
+			if _, err := target.build(len(params) + 1); err != nil {
 				return "", nil, err
 			}
-			buf.WriteString(b.onConflictTarget.text)
+			buf.WriteString(target.text)
 			buf.WriteRune(' ')
 
-			params = append(params, b.onConflictTarget.params...)
+			params = append(params, target.params...)
 		}
 
 		buf.WriteString("DO NOTHING")
@@ -170,5 +217,8 @@ func (b *inserter) Build() (string, []interface{}, error) {
 			buf.WriteString(s)
 		}
 	}
-	return buf.String(), params, nil
+	if err := checkParams(params); err != nil {
+		return "", nil, err
+	}
+	return d.rebind(buf.String(), params)
 }
@@ -1,6 +1,7 @@
 package builder
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -175,4 +176,97 @@ func TestInsert(t *testing.T) {
 			})
 		})
 	})
+
+	t.Run("WithExpr", func(t *testing.T) {
+		expectedSql := "INSERT INTO table1 (a, b, c) VALUES ($1, now(), nextval($2)), ($3, $4::jsonb, DEFAULT)"
+		b := Insert("table1").
+			Columns("a", "b", "c").
+			Values(1, Expr("now()"), Expr("nextval($1)", "seq")).
+			Values(2, Expr("$1::jsonb", `{"a":1}`), Default(0))
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 4); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("WithSubqueryExpr", func(t *testing.T) {
+		expectedSql := "INSERT INTO table1 (a, b) VALUES ($1, (SELECT id FROM table2 WHERE name = $2 AND kind IN ($3,$4)))"
+		b := Insert("table1").
+			Columns("a", "b").
+			Values(1, Expr("(SELECT id FROM table2 WHERE name = $1 AND kind IN ($2))", "x", []int{1, 2}))
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 4); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("WithInvalidExpr", func(t *testing.T) {
+		b := Insert("table1").
+			Columns("a", "b").
+			Values(1, Expr("nextval($2)", "seq"))
+
+		if _, _, err := b.Build(); err == nil {
+			t.Fatal("expected err not to be nil")
+		}
+	})
+
+	t.Run("TooManyParams", func(t *testing.T) {
+		b := Insert("table1").Columns("a", "b", "c", "d", "e", "f", "g", "h")
+		for i := 0; i < 10000; i++ {
+			b.Values(i, i, i, i, i, i, i, i)
+		}
+
+		_, _, err := b.Build()
+		if !errors.Is(err, ErrTooManyParams) {
+			t.Fatalf("expected err to be %v, got %v", ErrTooManyParams, err)
+		}
+	})
+
+	t.Run("Chunks", func(t *testing.T) {
+		b := Insert("table1").
+			Columns("a", "b").
+			Values(1, "a").
+			Values(2, "b").
+			Values(3, "c").
+			OnConflictDoNothing("(a) WHERE a > $1", 0)
+
+		chunks, err := b.Chunks(2)
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+		if len(chunks) != 2 {
+			t.Fatalf("expected %d chunks, got %d", 2, len(chunks))
+		}
+
+		expected := []struct {
+			sql    string
+			params int
+		}{
+			{"INSERT INTO table1 (a, b) VALUES ($1, $2), ($3, $4) ON CONFLICT (a) WHERE a > $5 DO NOTHING", 5},
+			{"INSERT INTO table1 (a, b) VALUES ($1, $2) ON CONFLICT (a) WHERE a > $3 DO NOTHING", 3},
+		}
+		for i, c := range chunks {
+			sql, params, err := c.Build()
+			if err != nil {
+				t.Fatalf("chunk %d: expected err to be nil, got %v", i, err)
+			}
+			if err := validateBuilderResult(sql, expected[i].sql, len(params), expected[i].params); err != nil {
+				t.Errorf("chunk %d: %v", i, err)
+			}
+		}
+
+		if _, err := b.Chunks(0); err == nil {
+			t.Error("expected err not to be nil for zero chunk size")
+		}
+	})
 }
@@ -0,0 +1,112 @@
+package builder
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// JSONValue is a parameter which is marshalled to JSON when bound.
+type JSONValue struct {
+	v interface{}
+}
+
+// JSON returns a JSONValue for v, e.g. Where("data @> $1::jsonb", JSON(filter)).
+func JSON(v interface{}) JSONValue {
+	return JSONValue{v}
+}
+
+// Value implements driver.Valuer. JSON is bound as text so that it can be cast
+// to json or jsonb.
+func (j JSONValue) Value() (driver.Value, error) {
+	b, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// The helpers below return expressions which can be used as parameters of Where,
+// Columns and other builder methods, e.g. Where("$1", JSONContains("data", v)).
+// col is written as-is and must not come from user input.
+
+// JSONGet returns col -> key [-> key ...] expression. Path elements are object keys
+// (strings) or array indexes (integers).
+func JSONGet(col string, path ...interface{}) ExprValue {
+	return jsonGet(col, "->", path)
+}
+
+// JSONGetText returns col -> key [...] ->> key expression, which returns the last
+// path element as text.
+func JSONGetText(col string, path ...interface{}) ExprValue {
+	return jsonGet(col, "->>", path)
+}
+
+func jsonGet(col, last string, path []interface{}) ExprValue {
+	var sb strings.Builder
+	sb.WriteString(col)
+	for i, p := range path {
+		op := " -> "
+		if i == len(path)-1 {
+			op = " " + last + " "
+		}
+		sb.WriteString(op)
+		sb.WriteString(placeholder(i+1, p))
+	}
+	return Expr(sb.String(), path...)
+}
+
+// placeholder returns $idx placeholder cast to text or int according to p type,
+// which selects the matching JSON operator.
+func placeholder(idx int, p interface{}) string {
+	ph := "$" + strconv.Itoa(idx)
+	switch p.(type) {
+	case string:
+		return ph + "::text"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return ph + "::int"
+	}
+	return ph
+}
+
+// JSONPath returns col #> path expression.
+func JSONPath(col string, path ...string) ExprValue {
+	return Expr(col+" #> $1::text[]", pq.StringArray(path))
+}
+
+// JSONPathText returns col #>> path expression, which returns the value as text.
+func JSONPathText(col string, path ...string) ExprValue {
+	return Expr(col+" #>> $1::text[]", pq.StringArray(path))
+}
+
+// JSONContains returns col @> v condition, v is marshalled to JSON.
+func JSONContains(col string, v interface{}) ExprValue {
+	return Expr(col+" @> $1::jsonb", JSON(v))
+}
+
+// JSONHasKey returns col ? key condition.
+func JSONHasKey(col string, key string) ExprValue {
+	return Expr(col+" ? $1::text", key)
+}
+
+// JSONHasAnyKey returns col ?| keys condition.
+func JSONHasAnyKey(col string, keys ...string) ExprValue {
+	return Expr(col+" ?| $1::text[]", pq.StringArray(keys))
+}
+
+// JSONHasAllKeys returns col ?& keys condition.
+func JSONHasAllKeys(col string, keys ...string) ExprValue {
+	return Expr(col+" ?& $1::text[]", pq.StringArray(keys))
+}
+
+// JSONPathExists returns jsonb_path_exists(col, path [, vars]) condition. vars is
+// marshalled to JSON and omitted if nil.
+func JSONPathExists(col string, path string, vars interface{}) ExprValue {
+	if vars == nil {
+		return Expr("jsonb_path_exists("+col+", $1::jsonpath)", path)
+	}
+	return Expr("jsonb_path_exists("+col+", $1::jsonpath, $2::jsonb)", path, JSON(vars))
+}
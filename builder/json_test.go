@@ -0,0 +1,76 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestJSON(t *testing.T) {
+	v, err := JSON(map[string]interface{}{"a": []int{1, 2}}).Value()
+	if err != nil {
+		t.Fatalf("expected err to be nil, got %v", err)
+	}
+	if v != `{"a":[1,2]}` {
+		t.Errorf("unexpected JSON value %v", v)
+	}
+
+	if _, err := JSON(func() {}).Value(); err == nil {
+		t.Error("expected marshalling error, got nil")
+	}
+}
+
+func TestJSONHelpers(t *testing.T) {
+	examples := []struct {
+		name     string
+		b        Builder
+		expected string
+		params   []interface{}
+	}{
+		{
+			"Get",
+			Select("id").Columns("$1 AS city", JSONGetText("data", "address", "city")).From("t").Where("$1 = $2", JSONGet("data", "tags", 0), JSON("x")),
+			"SELECT id, data -> $1::text ->> $2::text AS city FROM t WHERE (data -> $3::text -> $4::int = $5)",
+			[]interface{}{"address", "city", "tags", 0, JSON("x")},
+		},
+		{
+			"Path",
+			Select().Columns("$1", JSONPath("data", "a", "b")).From("t").Where("$1 = 'x'", JSONPathText("data", "c")),
+			"SELECT data #> $1::text[] FROM t WHERE (data #>> $2::text[] = 'x')",
+			[]interface{}{pq.StringArray{"a", "b"}, pq.StringArray{"c"}},
+		},
+		{
+			"Contains",
+			Select("id").From("t").Where("$1 AND $2", JSONContains("data", map[string]int{"a": 1}), JSONHasKey("data", "b")),
+			"SELECT id FROM t WHERE (data @> $1::jsonb AND data ? $2::text)",
+			[]interface{}{JSON(map[string]int{"a": 1}), "b"},
+		},
+		{
+			"Keys",
+			Select("id").From("t").Where("$1 OR $2", JSONHasAnyKey("data", "a", "b"), JSONHasAllKeys("data", "c")),
+			"SELECT id FROM t WHERE (data ?| $1::text[] OR data ?& $2::text[])",
+			[]interface{}{pq.StringArray{"a", "b"}, pq.StringArray{"c"}},
+		},
+		{
+			"PathExists",
+			Select("id").From("t").Where("$1", JSONPathExists("data", "$.a ? (@ > $min)", map[string]int{"min": 1})).Where("$1", JSONPathExists("data", "$.b", nil)),
+			"SELECT id FROM t WHERE (jsonb_path_exists(data, $1::jsonpath, $2::jsonb)) AND (jsonb_path_exists(data, $3::jsonpath))",
+			[]interface{}{"$.a ? (@ > $min)", JSON(map[string]int{"min": 1}), "$.b"},
+		},
+	}
+	for _, x := range examples {
+		t.Run(x.name, func(t *testing.T) {
+			sql, params, err := x.b.Build()
+			if err != nil {
+				t.Fatalf("expected err to be nil, got %v", err)
+			}
+			if err := validateBuilderResult(sql, x.expected, len(params), len(x.params)); err != nil {
+				t.Error(err)
+			}
+			if !reflect.DeepEqual(params, x.params) {
+				t.Errorf("expected params %v, got %v", x.params, params)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package builder
 
 import (
 	"bytes"
+	"math"
 	"strconv"
 )
 
@@ -21,7 +22,7 @@ type selecter struct {
 	groupBy  []string
 	having   exprs
 	union    unions
-	orderBy  []string
+	orderBy  exprs
 	offset   uint64
 	limit    uint64
 	locking  string
@@ -70,8 +71,8 @@ func (b *selecter) Union(all bool, q Selecter) Selecter {
 	return b
 }
 
-func (b *selecter) OrderBy(orderBy string) Selecter {
-	b.orderBy = append(b.orderBy, orderBy)
+func (b *selecter) OrderBy(orderBy string, params ...interface{}) Selecter {
+	b.orderBy = append(b.orderBy, &expr{orderBy, params})
 	return b
 }
 
@@ -91,6 +92,19 @@ func (b *selecter) For(locking string) Selecter {
 }
 
 func (b *selecter) Build() (string, []interface{}, error) {
+	return b.BuildDialect(Postgres)
+}
+
+func (b *selecter) BuildDialect(d Dialect) (string, []interface{}, error) {
+	// verify
+	if len(b.distinct) > 0 && d != Postgres {
+		return "", nil, d.unsupported("DISTINCT ON")
+	}
+
+	if b.locking != "" && d == SQLite {
+		return "", nil, d.unsupported("FOR " + b.locking)
+	}
+
 	// build
 	var params []interface{}
 	var buf bytes.Buffer
@@ -128,10 +142,11 @@ func (b *selecter) Build() (string, []interface{}, error) {
 	if len(b.columns) > 0 {
 		buf.WriteRune(' ')
 		// validate and rename SELECT expressions
-		if err := b.columns.build(len(params) + 1); err != nil {
+		columns, err := b.columns.build(len(params) + 1)
+		if err != nil {
 			return "", nil, err
 		}
-		for i, x := range b.columns {
+		for i, x := range columns {
 			if i > 0 {
 				buf.WriteString(", ")
 			}
@@ -143,12 +158,13 @@ func (b *selecter) Build() (string, []interface{}, error) {
 	// from
 	if len(b.from) > 0 {
 		// validate and rename from conditions
-		if err := b.from.build(len(params) + 1); err != nil {
+		from, err := b.from.build(len(params) + 1)
+		if err != nil {
 			return "", nil, err
 		}
 
 		buf.WriteString(" FROM ")
-		for i, x := range b.from {
+		for i, x := range from {
 			if i > 0 {
 				buf.WriteRune(' ')
 			}
@@ -160,12 +176,13 @@ func (b *selecter) Build() (string, []interface{}, error) {
 	// where
 	if len(b.where) > 0 {
 		// validate and rename where conditions
-		if err := b.where.build(len(params) + 1); err != nil {
+		where, err := b.where.build(len(params) + 1)
+		if err != nil {
 			return "", nil, err
 		}
 
 		buf.WriteString(" WHERE (")
-		for i, x := range b.where {
+		for i, x := range where {
 			if i > 0 {
 				buf.WriteString(") AND (")
 			}
@@ -189,12 +206,13 @@ func (b *selecter) Build() (string, []interface{}, error) {
 	// having
 	if len(b.having) > 0 {
 		// validate and rename where conditions
-		if err := b.having.build(len(params) + 1); err != nil {
+		having, err := b.having.build(len(params) + 1)
+		if err != nil {
 			return "", nil, err
 		}
 
 		buf.WriteString(" HAVING ")
-		for i, x := range b.having {
+		for i, x := range having {
 			if i > 0 {
 				buf.WriteString(" AND ")
 			}
@@ -228,25 +246,49 @@ func (b *selecter) Build() (string, []interface{}, error) {
 
 	// order by
 	if len(b.orderBy) > 0 {
+		// validate and rename order by expressions
+		orderBy, err := b.orderBy.build(len(params) + 1)
+		if err != nil {
+			return "", nil, err
+		}
+
 		buf.WriteString(" ORDER BY ")
-		for i, s := range b.orderBy {
+		for i, x := range orderBy {
 			if i > 0 {
 				buf.WriteString(", ")
 			}
-			buf.WriteString(s)
+			params = append(params, x.params...)
+			buf.WriteString(x.text)
 		}
 	}
 
-	// offset
-	if b.offset > 0 {
-		buf.WriteString(" OFFSET ")
-		buf.WriteString(strconv.FormatUint(b.offset, 10))
-	}
+	if d == Postgres {
+		// offset
+		if b.offset > 0 {
+			buf.WriteString(" OFFSET ")
+			buf.WriteString(strconv.FormatUint(b.offset, 10))
+		}
 
-	// limit
-	if b.limit > 0 {
+		// limit
+		if b.limit > 0 {
+			buf.WriteString(" LIMIT ")
+			buf.WriteString(strconv.FormatUint(b.limit, 10))
+		}
+	} else if b.limit > 0 || b.offset > 0 {
+		// SQLite and MySQL require LIMIT before OFFSET and do not allow OFFSET alone
 		buf.WriteString(" LIMIT ")
-		buf.WriteString(strconv.FormatUint(b.limit, 10))
+		switch {
+		case b.limit > 0:
+			buf.WriteString(strconv.FormatUint(b.limit, 10))
+		case d == SQLite:
+			buf.WriteString("-1")
+		default:
+			buf.WriteString(strconv.FormatUint(math.MaxUint64, 10))
+		}
+		if b.offset > 0 {
+			buf.WriteString(" OFFSET ")
+			buf.WriteString(strconv.FormatUint(b.offset, 10))
+		}
 	}
 
 	// for
@@ -255,5 +297,8 @@ func (b *selecter) Build() (string, []interface{}, error) {
 		buf.WriteString(b.locking)
 	}
 
-	return buf.String(), params, nil
+	if err := checkParams(params); err != nil {
+		return "", nil, err
+	}
+	return d.rebind(buf.String(), params)
 }
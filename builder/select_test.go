@@ -208,4 +208,51 @@ func TestSelect(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("BuildTwice", func(t *testing.T) {
+		expectedSql := "WITH t2 AS (SELECT id FROM table2 WHERE (a = $1) AND (b = $2)) SELECT * FROM table1 WHERE (x = $3) AND (y IN ($4,$5))"
+		b := Select("*").
+			With("t2", Select("id").From("table2").Where("a = $1", 1).Where("b = $1", 2)).
+			From("table1").
+			Where("x = $1", 3).
+			Where("y IN ($1)", []int{4, 5})
+
+		for i := 0; i < 2; i++ {
+			sql, params, err := b.Build()
+			if err != nil {
+				t.Fatalf("build %d: expected err to be nil, got %v", i, err)
+			}
+
+			if err := validateBuilderResult(sql, expectedSql, len(params), 5); err != nil {
+				t.Errorf("build %d: %v", i, err)
+			}
+		}
+	})
+
+	t.Run("RepeatedParams", func(t *testing.T) {
+		expectedSql := "SELECT * FROM table1 WHERE (a = $1) AND (b = $2 OR c = $2)"
+		b := Select("*").
+			From("table1").
+			Where("a = $1", 1).
+			Where("b = $1 OR c = $1", "x")
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 2); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("UnreferencedParams", func(t *testing.T) {
+		b := Select("*").
+			From("table1").
+			Where("a = $1", 1, 2)
+
+		if _, _, err := b.Build(); err == nil {
+			t.Fatal("expected err not to be nil")
+		}
+	})
 }
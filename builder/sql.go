@@ -5,8 +5,16 @@ type sqler struct {
 }
 
 func (b *sqler) Build() (string, []interface{}, error) {
-	if _, err := b.query.build(1); err != nil {
+	return b.BuildDialect(Postgres)
+}
+
+func (b *sqler) BuildDialect(d Dialect) (string, []interface{}, error) {
+	query := b.query
+	if _, err := query.build(1); err != nil {
+		return "", nil, err
+	}
+	if err := checkParams(query.params); err != nil {
 		return "", nil, err
 	}
-	return b.query.text, b.query.params, nil
+	return d.rebind(query.text, query.params)
 }
@@ -0,0 +1,85 @@
+package builder
+
+import "strings"
+
+// TextSearchConfig generates full-text search expressions for a text search
+// configuration. Expressions are used as parameters of Where, Columns and OrderBy,
+// e.g. Where("$1", TextSearch("english").Match("body", q)). Documents are written
+// as-is and must not come from user input, queries are bound as parameters.
+type TextSearchConfig struct {
+	config  string
+	toQuery string
+	vector  bool
+}
+
+// TextSearch returns TextSearchConfig for the configuration, e.g. "english". Queries
+// are parsed with websearch_to_tsquery unless Plain is used.
+func TextSearch(config string) TextSearchConfig {
+	return TextSearchConfig{config: config, toQuery: "websearch_to_tsquery"}
+}
+
+// Plain returns a copy of ts which parses queries with plainto_tsquery.
+func (ts TextSearchConfig) Plain() TextSearchConfig {
+	ts.toQuery = "plainto_tsquery"
+	return ts
+}
+
+// Vector returns a copy of ts which treats documents as tsvector expressions, such
+// as a stored generated column, instead of calling to_tsvector.
+func (ts TextSearchConfig) Vector() TextSearchConfig {
+	ts.vector = true
+	return ts
+}
+
+// Match returns document @@ query condition.
+func (ts TextSearchConfig) Match(doc, query string) ExprValue {
+	return Expr(ts.tsvector(doc)+" @@ "+ts.tsquery(), query)
+}
+
+// Rank returns ts_rank(document, query) expression.
+func (ts TextSearchConfig) Rank(doc, query string) ExprValue {
+	return Expr("ts_rank("+ts.tsvector(doc)+", "+ts.tsquery()+")", query)
+}
+
+// RankCD returns ts_rank_cd(document, query) expression.
+func (ts TextSearchConfig) RankCD(doc, query string) ExprValue {
+	return Expr("ts_rank_cd("+ts.tsvector(doc)+", "+ts.tsquery()+")", query)
+}
+
+// Headline returns ts_headline snippet expression for doc, which must be a text
+// expression. options, e.g. "MaxWords=20, MinWords=5", are omitted if empty.
+func (ts TextSearchConfig) Headline(doc, query, options string) ExprValue {
+	if options == "" {
+		return Expr("ts_headline("+ts.literal()+", "+doc+", "+ts.tsquery()+")", query)
+	}
+	return Expr("ts_headline("+ts.literal()+", "+doc+", "+ts.tsquery()+", $2)", query, options)
+}
+
+func (ts TextSearchConfig) tsvector(doc string) string {
+	if ts.vector {
+		return doc
+	}
+	return "to_tsvector(" + ts.literal() + ", " + doc + ")"
+}
+
+// tsquery returns the query expression, query is the first parameter.
+func (ts TextSearchConfig) tsquery() string {
+	return ts.toQuery + "(" + ts.literal() + ", $1)"
+}
+
+// literal returns the configuration as a string literal rather than a parameter,
+// so that expression indexes on to_tsvector can be used.
+func (ts TextSearchConfig) literal() string {
+	return "'" + strings.ReplaceAll(ts.config, "'", "''") + "'::regconfig"
+}
+
+// Similar returns col % value pg_trgm condition, which is true if similarity of col
+// and value exceeds pg_trgm.similarity_threshold.
+func Similar(col, value string) ExprValue {
+	return Expr(col+" % $1", value)
+}
+
+// Similarity returns similarity(col, value) pg_trgm expression.
+func Similarity(col, value string) ExprValue {
+	return Expr("similarity("+col+", $1)", value)
+}
@@ -0,0 +1,70 @@
+package builder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTextSearch(t *testing.T) {
+	ts := TextSearch("english")
+
+	examples := []struct {
+		name     string
+		b        Builder
+		expected string
+		params   []interface{}
+	}{
+		{
+			"Match",
+			Select("id").
+				Columns("$1 AS snippet", ts.Headline("body", "cats dogs", "MaxWords=20")).
+				From("docs").
+				Where("$1", ts.Match("body", "cats dogs")).
+				OrderBy("$1 DESC", ts.Rank("body", "cats dogs")),
+			"SELECT id, ts_headline('english'::regconfig, body, websearch_to_tsquery('english'::regconfig, $1), $2) AS snippet FROM docs " +
+				"WHERE (to_tsvector('english'::regconfig, body) @@ websearch_to_tsquery('english'::regconfig, $3)) " +
+				"ORDER BY ts_rank(to_tsvector('english'::regconfig, body), websearch_to_tsquery('english'::regconfig, $4)) DESC",
+			[]interface{}{"cats dogs", "MaxWords=20", "cats dogs", "cats dogs"},
+		},
+		{
+			"PlainVector",
+			Select("id").
+				From("docs").
+				Where("$1", ts.Plain().Vector().Match("search", "cat")).
+				OrderBy("$1 DESC", ts.Vector().RankCD("search", "cat")),
+			"SELECT id FROM docs WHERE (search @@ plainto_tsquery('english'::regconfig, $1)) " +
+				"ORDER BY ts_rank_cd(search, websearch_to_tsquery('english'::regconfig, $2)) DESC",
+			[]interface{}{"cat", "cat"},
+		},
+		{
+			"QuotedConfig",
+			Select("id").From("docs").Where("$1", TextSearch("it's").Match("body", "x")),
+			"SELECT id FROM docs WHERE (to_tsvector('it''s'::regconfig, body) @@ websearch_to_tsquery('it''s'::regconfig, $1))",
+			[]interface{}{"x"},
+		},
+		{
+			"Trigram",
+			Select("id").
+				Columns("$1 AS sml", Similarity("name", "jon")).
+				From("users").
+				Where("$1 AND name NOT LIKE '%test%'", Similar("name", "jon")).
+				OrderBy("sml DESC"),
+			"SELECT id, similarity(name, $1) AS sml FROM users WHERE (name % $2 AND name NOT LIKE '%test%') ORDER BY sml DESC",
+			[]interface{}{"jon", "jon"},
+		},
+	}
+	for _, x := range examples {
+		t.Run(x.name, func(t *testing.T) {
+			sql, params, err := x.b.Build()
+			if err != nil {
+				t.Fatalf("expected err to be nil, got %v", err)
+			}
+			if err := validateBuilderResult(sql, x.expected, len(params), len(x.params)); err != nil {
+				t.Error(err)
+			}
+			if !reflect.DeepEqual(params, x.params) {
+				t.Errorf("expected params %v, got %v", x.params, params)
+			}
+		})
+	}
+}
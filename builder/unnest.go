@@ -0,0 +1,182 @@
+package builder
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// buildUnnest writes "SELECT * FROM unnest($1::type[], ...)" for rows to buf and returns params
+// with one array parameter per column appended. Column types are inferred from the first non-nil
+// value of each column unless types are provided.
+func buildUnnest(buf *bytes.Buffer, rows [][]interface{}, types []string, params []interface{}) ([]interface{}, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("values required for unnest")
+	}
+
+	ncols := len(rows[0])
+	for _, row := range rows {
+		if len(row) != ncols {
+			return nil, fmt.Errorf("invalid number of values, expected %d, got %d", ncols, len(row))
+		}
+	}
+	if len(types) > 0 && len(types) != ncols {
+		return nil, fmt.Errorf("invalid number of unnest types, expected %d, got %d", ncols, len(types))
+	}
+
+	buf.WriteString("SELECT * FROM unnest(")
+	for i := 0; i < ncols; i++ {
+		col := make([]interface{}, len(rows))
+		for j, row := range rows {
+			switch row[i].(type) {
+			case DefaultValue:
+				return nil, errors.New("DEFAULT values are not supported with unnest")
+			case ExprValue:
+				return nil, errors.New("expression values are not supported with unnest")
+			}
+			col[j] = row[i]
+		}
+
+		var typ string
+		if len(types) > 0 {
+			typ = types[i]
+		} else {
+			var err error
+			if typ, err = inferType(col); err != nil {
+				return nil, fmt.Errorf("column %d: %v", i+1, err)
+			}
+		}
+		if isBlank(typ) {
+			return nil, errors.New("empty unnest type")
+		}
+
+		arr, err := unnestArray(col, typ)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %v", i+1, err)
+		}
+
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		params = append(params, arr)
+		buf.WriteRune('$')
+		buf.WriteString(strconv.Itoa(len(params)))
+		buf.WriteString("::")
+		buf.WriteString(typ)
+		buf.WriteString("[]")
+	}
+	buf.WriteRune(')')
+
+	return params, nil
+}
+
+// unnestArray converts column values to an array parameter.
+func unnestArray(col []interface{}, typ string) (interface{}, error) {
+	if strings.EqualFold(strings.TrimSpace(typ), "bytea") {
+		arr := make(byteaArray, len(col))
+		for i, v := range col {
+			v = deref(v)
+			if v == nil {
+				continue // NULL
+			}
+			b, ok := v.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("expected []byte, got %T", v)
+			}
+			arr[i] = b
+		}
+		return arr, nil
+	}
+	for i, v := range col {
+		col[i] = deref(v)
+	}
+	return pq.GenericArray{A: col}, nil
+}
+
+// byteaArray is a bytea[] parameter like pq.ByteaArray, which writes nil elements as NULL.
+type byteaArray [][]byte
+
+func (a byteaArray) Value() (driver.Value, error) {
+	var buf bytes.Buffer
+	buf.WriteRune('{')
+	for i, b := range a {
+		if i > 0 {
+			buf.WriteRune(',')
+		}
+		if b == nil {
+			buf.WriteString("NULL")
+			continue
+		}
+		buf.WriteString(`"\\x`)
+		buf.WriteString(hex.EncodeToString(b))
+		buf.WriteRune('"')
+	}
+	buf.WriteRune('}')
+	return buf.Bytes(), nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// inferType returns PostgreSQL type of the first non-nil value in col.
+func inferType(col []interface{}) (string, error) {
+	for _, v := range col {
+		v = deref(v)
+		if v == nil {
+			continue
+		}
+		t := reflect.TypeOf(v)
+		switch t.Kind() {
+		case reflect.Bool:
+			return "boolean", nil
+		case reflect.Int8, reflect.Int16, reflect.Uint8:
+			return "smallint", nil
+		case reflect.Int32, reflect.Uint16:
+			return "integer", nil
+		case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			return "bigint", nil
+		case reflect.Float32:
+			return "real", nil
+		case reflect.Float64:
+			return "double precision", nil
+		case reflect.String:
+			return "text", nil
+		case reflect.Slice:
+			if t.Elem().Kind() == reflect.Uint8 {
+				return "bytea", nil
+			}
+		case reflect.Struct:
+			if t == timeType {
+				return "timestamptz", nil
+			}
+		}
+		return "", fmt.Errorf("cannot infer unnest type for %T", v)
+	}
+	return "", errors.New("cannot infer unnest type for NULL values")
+}
+
+// deref returns the value v points to, or nil for nil pointers. driver.Valuer
+// values are returned as-is.
+func deref(v interface{}) interface{} {
+	if _, ok := v.(driver.Valuer); ok {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
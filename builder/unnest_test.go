@@ -0,0 +1,120 @@
+package builder
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestUnnest(t *testing.T) {
+	t.Run("Inferred", func(t *testing.T) {
+		expectedSql := "INSERT INTO table1 (a, b, c, d) SELECT * FROM unnest($1::bigint[], $2::text[], $3::timestamptz[], $4::boolean[])"
+		s := "bbb"
+		b := Insert("table1").
+			Columns("a", "b", "c", "d").
+			Values(1, nil, time.Now(), true).
+			Values(2, &s, time.Now(), false).
+			Unnest()
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 4); err != nil {
+			t.Error(err)
+		}
+
+		v, err := params[1].(pq.GenericArray).Value()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+		if v != `{NULL,"bbb"}` {
+			t.Errorf("expected array %q, got %q", `{NULL,"bbb"}`, v)
+		}
+	})
+
+	t.Run("Explicit", func(t *testing.T) {
+		expectedSql := "INSERT INTO table1 (a, b) SELECT * FROM unnest($1::int[], $2::varchar(10)[]) RETURNING id"
+		b := Insert("table1").
+			Columns("a", "b").
+			Values(1, nil).
+			Values(2, nil).
+			Unnest("int", "varchar(10)").
+			Returning("id")
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 2); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		expectedSql := "INSERT INTO table1 (a, b) SELECT * FROM unnest($1::bigint[], $2::double precision[]) ON CONFLICT (a) DO UPDATE SET a = EXCLUDED.a, b = EXCLUDED.b"
+		b := Upsert("table1", "(a)").
+			Columns("a", "b").
+			Values(int64(1), 1.5).
+			Values(int64(2), 2.5).
+			Unnest()
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 2); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("NullBytea", func(t *testing.T) {
+		b := Insert("table1").
+			Columns("a").
+			Values([]byte{0x01, 0xff}).
+			Values(nil).
+			Values([]byte(nil)).
+			Unnest("bytea")
+
+		_, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+		v, err := params[0].(driver.Valuer).Value()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+		if expected := `{"\\x01ff",NULL,NULL}`; string(v.([]byte)) != expected {
+			t.Errorf("expected array %s, got %s", expected, v)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		examples := []struct {
+			b   Builder
+			err string
+		}{
+			{Insert("table1").Columns("a").Unnest(), "values required for unnest"},
+			{Insert("table1").Columns("a").Values(nil).Unnest(), "column 1: cannot infer unnest type for NULL values"},
+			{Insert("table1").Columns("a").Values(struct{}{}).Unnest(), "column 1: cannot infer unnest type for struct {}"},
+			{Insert("table1").Columns("a").Values(Default(0)).Unnest("int"), "DEFAULT values are not supported with unnest"},
+			{Insert("table1").Columns("a").Values(Expr("now()")).Unnest("int"), "expression values are not supported with unnest"},
+			{Insert("table1").Columns("a").Values(1).Unnest("int", "text"), "invalid number of unnest types, expected 1, got 2"},
+		}
+
+		for i, x := range examples {
+			_, _, err := x.b.Build()
+			if err == nil {
+				t.Fatalf("example %d: expected err not to be nil", i)
+			}
+			if err.Error() != x.err {
+				t.Errorf("example %d: expected error %q, got %q", i, x.err, err.Error())
+			}
+		}
+	})
+}
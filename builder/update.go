@@ -43,6 +43,10 @@ func (b *updater) Returning(returning ...string) Updater {
 }
 
 func (b *updater) Build() (string, []interface{}, error) {
+	return b.BuildDialect(Postgres)
+}
+
+func (b *updater) BuildDialect(d Dialect) (string, []interface{}, error) {
 	// verify
 	if isBlank(b.table) {
 		return "", nil, errors.New("empty table")
@@ -52,6 +56,14 @@ func (b *updater) Build() (string, []interface{}, error) {
 		return "", nil, errors.New("empty set")
 	}
 
+	if len(b.from) > 0 && d == MySQL {
+		return "", nil, d.unsupported("UPDATE ... FROM")
+	}
+
+	if err := d.returning(b.returning); err != nil {
+		return "", nil, err
+	}
+
 	// build
 	var params []interface{}
 	var buf bytes.Buffer
@@ -74,12 +86,13 @@ func (b *updater) Build() (string, []interface{}, error) {
 	// set
 	if len(b.set) > 0 {
 		// validate and rename set conditions
-		if err := b.set.build(len(params) + 1); err != nil {
+		set, err := b.set.build(len(params) + 1)
+		if err != nil {
 			return "", nil, err
 		}
 
 		buf.WriteString(" SET ")
-		for i, x := range b.set {
+		for i, x := range set {
 			if i > 0 {
 				buf.WriteString(", ")
 			}
@@ -91,12 +104,13 @@ func (b *updater) Build() (string, []interface{}, error) {
 	// from
 	if len(b.from) > 0 {
 		// validate and rename from conditions
-		if err := b.from.build(len(params) + 1); err != nil {
+		from, err := b.from.build(len(params) + 1)
+		if err != nil {
 			return "", nil, err
 		}
 
 		buf.WriteString(" FROM ")
-		for i, x := range b.from {
+		for i, x := range from {
 			if i > 0 {
 				buf.WriteRune(' ')
 			}
@@ -108,12 +122,13 @@ func (b *updater) Build() (string, []interface{}, error) {
 	// where
 	if len(b.where) > 0 {
 		// validate and rename where conditions
-		if err := b.where.build(len(params) + 1); err != nil {
+		where, err := b.where.build(len(params) + 1)
+		if err != nil {
 			return "", nil, err
 		}
 
 		buf.WriteString(" WHERE (")
-		for i, x := range b.where {
+		for i, x := range where {
 			if i > 0 {
 				buf.WriteString(") AND (")
 			}
@@ -134,6 +149,9 @@ func (b *updater) Build() (string, []interface{}, error) {
 		}
 	}
 
-	return buf.String(), params, nil
+	if err := checkParams(params); err != nil {
+		return "", nil, err
+	}
+	return d.rebind(buf.String(), params)
 
 }
@@ -157,4 +157,22 @@ func TestUpdate(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("WithExpr", func(t *testing.T) {
+		expectedSql := "UPDATE table1 SET a = $1, b = now(), c = coalesce(c, $2) + $3 WHERE (id = $4)"
+		b := Update("table1").
+			Set("a = $1", 1).
+			Set("b = $1", Expr("now()")).
+			Set("c = $1 + $2", Expr("coalesce(c, $1)", 0), 5).
+			Where("id = $1", 10)
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 4); err != nil {
+			t.Error(err)
+		}
+	})
 }
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"strconv"
 )
 
 type upserter struct {
@@ -12,6 +11,8 @@ type upserter struct {
 	into             string
 	columns          []string
 	values           [][]interface{}
+	unnest           bool
+	unnestTypes      []string
 	from             Selecter
 	onConflictTarget *expr
 	onConflictUpdate *expr
@@ -33,6 +34,12 @@ func (b *upserter) Values(values ...interface{}) Upserter {
 	return b
 }
 
+func (b *upserter) Unnest(types ...string) Upserter {
+	b.unnest = true
+	b.unnestTypes = types
+	return b
+}
+
 func (b *upserter) From(q Selecter) Upserter {
 	b.from = q
 	return b
@@ -48,7 +55,25 @@ func (b *upserter) Returning(returning ...string) Upserter {
 	return b
 }
 
+func (b *upserter) Chunks(size int) ([]Builder, error) {
+	chunks, err := chunkRows(b.values, size)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Builder, len(chunks))
+	for i, rows := range chunks {
+		c := *b
+		c.values = rows
+		res[i] = &c
+	}
+	return res, nil
+}
+
 func (b *upserter) Build() (string, []interface{}, error) {
+	return b.BuildDialect(Postgres)
+}
+
+func (b *upserter) BuildDialect(d Dialect) (string, []interface{}, error) {
 	// verify
 	if len(b.columns) > 0 && len(b.values) > 0 {
 		for _, row := range b.values {
@@ -62,6 +87,14 @@ func (b *upserter) Build() (string, []interface{}, error) {
 		return "", nil, errors.New("values must be empty if from is specified")
 	}
 
+	if b.unnest && d != Postgres {
+		return "", nil, d.unsupported("unnest")
+	}
+
+	if err := d.returning(b.returning); err != nil {
+		return "", nil, err
+	}
+
 	if b.onConflictTarget != nil {
 		if isBlank(b.onConflictTarget.text) {
 			return "", nil, errors.New("empty ON CONFLICT target")
@@ -119,7 +152,13 @@ func (b *upserter) Build() (string, []interface{}, error) {
 	}
 
 	// values
-	if len(b.values) > 0 {
+	if b.unnest {
+		buf.WriteRune(' ')
+		var err error
+		if params, err = buildUnnest(&buf, b.values, b.unnestTypes, params); err != nil {
+			return "", nil, err
+		}
+	} else if len(b.values) > 0 {
 		buf.WriteString(" VALUES ")
 		for j, row := range b.values {
 			if j > 0 {
@@ -130,12 +169,9 @@ func (b *upserter) Build() (string, []interface{}, error) {
 				if i > 0 {
 					buf.WriteString(", ")
 				}
-				if _, ok := v.(DefaultValue); ok {
-					buf.WriteString("DEFAULT")
-				} else {
-					params = append(params, v)
-					buf.WriteRune('$')
-					buf.WriteString(strconv.Itoa(len(params)))
+				var err error
+				if params, err = buildValue(&buf, v, params); err != nil {
+					return "", nil, err
 				}
 			}
 			buf.WriteRune(')')
@@ -162,28 +198,50 @@ func (b *upserter) Build() (string, []interface{}, error) {
 		params = append(params, x.params...)
 	}
 
-	// on conflict: do nothing
-	if b.onConflictTarget != nil {
+	// on duplicate key: MySQL ignores conflict target as any unique key conflict
+	// triggers the update, EXCLUDED values are referred to with VALUES()
+	if b.onConflictTarget != nil && d == MySQL {
+		buf.WriteString(" ON DUPLICATE KEY UPDATE ")
+
+		if b.onConflictUpdate != nil {
+			update := *b.onConflictUpdate
+			if _, err := update.build(len(params) + 1); err != nil {
+				return "", nil, err
+			}
+			buf.WriteString(excludedToValues(update.text))
+			params = append(params, update.params...)
+		} else {
+			for i, s := range b.columns {
+				if i > 0 {
+					buf.WriteString(", ")
+				}
+				buf.WriteString(fmt.Sprintf("%s = VALUES(%s)", s, s))
+			}
+		}
+	} else if b.onConflictTarget != nil {
+		// on conflict: do update
 		buf.WriteString(" ON CONFLICT ")
 
 		// validate and rename target condition
-		if _, err := b.onConflictTarget.build(len(params) + 1); err != nil {
+		target := *b.onConflictTarget
+		if _, err := target.build(len(params) + 1); err != nil {
 			return "", nil, err
 		}
-		buf.WriteString(b.onConflictTarget.text)
-		params = append(params, b.onConflictTarget.params...)
+		buf.WriteString(target.text)
+		params = append(params, target.params...)
 
 		buf.WriteString(" DO UPDATE SET ")
 
 		// use update statement if provided
 		if b.onConflictUpdate != nil {
 			// validate and rename target condition
-			if _, err := b.onConflictUpdate.build(len(params) + 1); err != nil {
+			update := *b.onConflictUpdate
+			if _, err := update.build(len(params) + 1); err != nil {
 				return "", nil, err
 			}
 
-			buf.WriteString(b.onConflictUpdate.text)
-			params = append(params, b.onConflictUpdate.params...)
+			buf.WriteString(update.text)
+			params = append(params, update.params...)
 		} else {
 			// otherwise generate EXCLUDED for columns
 			for i, s := range b.columns {
@@ -206,5 +264,8 @@ func (b *upserter) Build() (string, []interface{}, error) {
 			buf.WriteString(s)
 		}
 	}
-	return buf.String(), params, nil
+	if err := checkParams(params); err != nil {
+		return "", nil, err
+	}
+	return d.rebind(buf.String(), params)
 }
@@ -130,4 +130,46 @@ func TestUpsert(t *testing.T) {
 			t.Error(err)
 		}
 	})
+
+	t.Run("WithExpr", func(t *testing.T) {
+		expectedSql := "INSERT INTO table1 (a, b, c) VALUES ($1, now(), $2::jsonb) ON CONFLICT (a) WHERE a > $3 DO UPDATE SET a = EXCLUDED.a, b = EXCLUDED.b, c = EXCLUDED.c"
+		b := Upsert("table1", "(a) WHERE a > $1", 0).
+			Columns("a", "b", "c").
+			Values(1, Expr("now()"), Expr("$1::jsonb", `{}`))
+
+		sql, params, err := b.Build()
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+
+		if err := validateBuilderResult(sql, expectedSql, len(params), 3); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("Chunks", func(t *testing.T) {
+		expectedSql := "INSERT INTO table1 (a, b) VALUES ($1, $2) ON CONFLICT (a) DO UPDATE SET b = $3"
+		b := Upsert("table1", "(a)").
+			Columns("a", "b").
+			Values(1, "a").
+			Values(2, "b").
+			Update("b = $1", "x")
+
+		chunks, err := b.Chunks(1)
+		if err != nil {
+			t.Fatalf("expected err to be nil, got %v", err)
+		}
+		if len(chunks) != 2 {
+			t.Fatalf("expected %d chunks, got %d", 2, len(chunks))
+		}
+		for i, c := range chunks {
+			sql, params, err := c.Build()
+			if err != nil {
+				t.Fatalf("chunk %d: expected err to be nil, got %v", i, err)
+			}
+			if err := validateBuilderResult(sql, expectedSql, len(params), 3); err != nil {
+				t.Errorf("chunk %d: %v", i, err)
+			}
+		}
+	})
 }
@@ -0,0 +1,104 @@
+package prequel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+// InsertChunked splits VALUES rows of b into statements with at most chunkSize rows each
+// and executes them in a single transaction using this DB. If dest is not nil, it must be
+// a pointer to a slice, and rows returned by each statement (see Returning) are appended to it.
+// InsertChunked returns the total number of affected rows. If ctx carries a transaction
+// of this DB (see WithRunner), chunks are executed in a nested transaction of it.
+func (db *DB) InsertChunked(ctx context.Context, b builder.Chunker, chunkSize int, dest interface{}) (int64, error) {
+	chunks, err := b.Chunks(chunkSize)
+	if err != nil {
+		return 0, err
+	}
+	return insertChunks(ctx, joinSession(ctx, db, db), chunks, dest)
+}
+
+// InsertChunked splits VALUES rows of b into statements with at most chunkSize rows each
+// and executes them using this transaction. See DB.InsertChunked for details.
+func (tx *Tx) InsertChunked(ctx context.Context, b builder.Chunker, chunkSize int, dest interface{}) (int64, error) {
+	chunks, err := b.Chunks(chunkSize)
+	if err != nil {
+		return 0, err
+	}
+	return doInsertChunked(ctx, tx, chunks, dest)
+}
+
+// InsertChunked splits VALUES rows of b into statements with at most chunkSize rows each
+// and executes them in a single transaction using this connection. See DB.InsertChunked for details.
+func (conn *Conn) InsertChunked(ctx context.Context, b builder.Chunker, chunkSize int, dest interface{}) (int64, error) {
+	chunks, err := b.Chunks(chunkSize)
+	if err != nil {
+		return 0, err
+	}
+	return insertChunks(ctx, joinSession(ctx, conn.db, conn), chunks, dest)
+}
+
+// insertChunks executes chunks using s, in a transaction begun with s if there are several.
+func insertChunks(ctx context.Context, s Session, chunks []builder.Builder, dest interface{}) (int64, error) {
+	if len(chunks) == 1 {
+		return doInsertChunked(ctx, s, chunks, dest)
+	}
+	tx, err := s.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return commitChunked(ctx, tx, chunks, dest)
+}
+
+// commitChunked executes chunks using tx and commits it, or rolls it back if
+// any of the chunks fails.
+func commitChunked(ctx context.Context, tx *Tx, chunks []builder.Builder, dest interface{}) (int64, error) {
+	n, err := doInsertChunked(ctx, tx, chunks, dest)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// doInsertChunked executes chunks one by one with r and sums their affected rows. If dest is
+// not nil, chunks are run with Select, which appends returned rows to dest.
+func doInsertChunked(ctx context.Context, r Runner, chunks []builder.Builder, dest interface{}) (int64, error) {
+	var slice reflect.Value
+	if dest != nil {
+		v := reflect.ValueOf(dest)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+			return 0, errors.New("dest must be a pointer to a slice")
+		}
+		slice = v.Elem()
+	}
+
+	var total int64
+	for _, b := range chunks {
+		if dest != nil {
+			n := slice.Len()
+			if err := r.Select(ctx, b, dest); err != nil {
+				return 0, err
+			}
+			total += int64(slice.Len() - n)
+			continue
+		}
+
+		res, err := r.Exec(ctx, b)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
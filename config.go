@@ -0,0 +1,197 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+// Option configures DB created with Open, Connect or NewDB. Transactions and connections
+// inherit the configuration of their DB.
+type Option func(*config)
+
+// QueryHook is called with the event of each executed statement after it is logged,
+// e.g. to collect metrics. Hooks must not modify the event.
+type QueryHook func(ctx context.Context, e *QueryEvent)
+
+type config struct {
+	db            *DB // DB configured with this config, used for plan capture
+	logger        Logger
+	hooks         []QueryHook
+	slowThreshold time.Duration
+	slow          slowLog
+	queryTimeout  time.Duration
+	txTimeout     time.Duration
+	pool          []func(db *sql.DB)
+	interceptors  []Interceptor
+}
+
+// WithLogger sets the logger of DB. The package logger set with SetLogger is used by default.
+func WithLogger(logger Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithHooks adds hooks called for each statement executed using DB.
+func WithHooks(hooks ...QueryHook) Option {
+	return func(c *config) {
+		c.hooks = append(c.hooks, hooks...)
+	}
+}
+
+// WithSlowQueryThreshold marks events of statements which take at least d as slow (see
+// QueryEvent.Slow). Slow statements are logged at warning level. Zero disables the check.
+// See WithSlowQueryOptions for sampling, rate limits and plan capture.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(c *config) {
+		c.slowThreshold = d
+	}
+}
+
+// WithQueryTimeout sets the timeout of statements run with a context which has no deadline.
+func WithQueryTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.queryTimeout = d
+	}
+}
+
+// WithTxTimeout sets the timeout of transactions begun with a context which has no deadline.
+// Transactions which are not committed or rolled back in time are rolled back by database/sql.
+func WithTxTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.txTimeout = d
+	}
+}
+
+// WithMaxOpenConns calls sql.DB.SetMaxOpenConns when DB is created.
+func WithMaxOpenConns(n int) Option {
+	return withPool(func(db *sql.DB) { db.SetMaxOpenConns(n) })
+}
+
+// WithMaxIdleConns calls sql.DB.SetMaxIdleConns when DB is created.
+func WithMaxIdleConns(n int) Option {
+	return withPool(func(db *sql.DB) { db.SetMaxIdleConns(n) })
+}
+
+// WithConnMaxLifetime calls sql.DB.SetConnMaxLifetime when DB is created.
+func WithConnMaxLifetime(d time.Duration) Option {
+	return withPool(func(db *sql.DB) { db.SetConnMaxLifetime(d) })
+}
+
+// WithConnMaxIdleTime calls sql.DB.SetConnMaxIdleTime when DB is created.
+func WithConnMaxIdleTime(d time.Duration) Option {
+	return withPool(func(db *sql.DB) { db.SetConnMaxIdleTime(d) })
+}
+
+func withPool(fn func(db *sql.DB)) Option {
+	return func(c *config) {
+		c.pool = append(c.pool, fn)
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// defaultLog is the package logger used by DBs created without WithLogger.
+var defaultLog = struct {
+	sync.RWMutex
+	logger Logger
+}{logger: newDefaultLogger()}
+
+// SetLogger sets the package logger used by DBs created without WithLogger.
+func SetLogger(logger Logger) {
+	defaultLog.Lock()
+	defaultLog.logger = logger
+	defaultLog.Unlock()
+}
+
+// SetLogLevel sets the level of the package logger.
+func SetLogLevel(lvl int) {
+	getLogger().SetLevel(lvl)
+}
+
+func getLogger() Logger {
+	defaultLog.RLock()
+	defer defaultLog.RUnlock()
+	return defaultLog.logger
+}
+
+// log returns the logger of c, or the package logger. It is safe to call on nil.
+func (c *config) log() Logger {
+	if c == nil || c.logger == nil {
+		return getLogger()
+	}
+	return c.logger
+}
+
+func (c *config) logf(start time.Time, format string, args ...interface{}) {
+	elapsed := time.Since(start)
+	c.log().Printf("%s %v", fmt.Sprintf(format, args...), elapsed)
+}
+
+// logQuery sends the event for the statement started at start to the logger and hooks.
+func (c *config) logQuery(ctx context.Context, start time.Time, kind QueryKind, sql string, params []interface{}, rows int64, err error) {
+	c.logStatement(ctx, nil, start, kind, sql, params, rows, err)
+}
+
+// logStatement is like logQuery, but captures the plan of a slow statement using q if
+// it is not nil and plan capture is enabled.
+func (c *config) logStatement(ctx context.Context, q sqlx.QueryerContext, start time.Time, kind QueryKind, sql string, params []interface{}, rows int64, err error) {
+	now := time.Now()
+	e := &QueryEvent{Kind: kind, Statement: builder.Classify(sql).Kind, SQL: sql, Params: params, Start: start, Duration: now.Sub(start), Rows: rows, Err: err}
+	if c != nil && c.slowThreshold > 0 && e.Duration >= c.slowThreshold && c.slow.allow(now) {
+		e.Slow = true
+		if c.canExplain(q, sql, err) {
+			if plan, err := c.explain(ctx, q, kind, sql, params); err == nil {
+				e.Plan = plan
+			} else {
+				c.log().Printf("EXPLAIN failed: %v", err)
+			}
+		}
+	}
+	if ql, ok := c.log().(QueryLogger); ok {
+		ql.LogQuery(ctx, e)
+	} else {
+		c.log().Printf("%s", e)
+	}
+	if c != nil {
+		for _, hook := range c.hooks {
+			hook(ctx, e)
+		}
+	}
+}
+
+// queryContext applies the default statement timeout to ctx.
+func (c *config) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c == nil {
+		return ctx, func() {}
+	}
+	return withDefaultTimeout(ctx, c.queryTimeout)
+}
+
+// txContext applies the default transaction timeout to ctx.
+func (c *config) txContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c == nil {
+		return ctx, func() {}
+	}
+	return withDefaultTimeout(ctx, c.txTimeout)
+}
+
+func withDefaultTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,83 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+type deadlineExecer struct {
+	deadline time.Time
+	ok       bool
+}
+
+func (e *deadlineExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.deadline, e.ok = ctx.Deadline()
+	return driver.RowsAffected(0), ctx.Err()
+}
+
+func TestOptions(t *testing.T) {
+	sqldb, err := sql.Open("postgres", "postgres://localhost/prequel?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqldb.Close()
+
+	var events []*QueryEvent
+	l := &captureLogger{}
+	pdb := NewDB(sqldb, "postgres",
+		WithLogger(l),
+		WithHooks(func(ctx context.Context, e *QueryEvent) { events = append(events, e) }),
+		WithSlowQueryThreshold(time.Nanosecond),
+		WithQueryTimeout(time.Minute),
+		WithMaxOpenConns(7),
+	)
+	if n := sqldb.Stats().MaxOpenConnections; n != 7 {
+		t.Errorf("expected %d max open connections, got %d", 7, n)
+	}
+
+	ctx := context.Background()
+	e := &deadlineExecer{}
+	doExecRaw(ctx, pdb.cfg, e, "SELECT 1")
+	if !e.ok || time.Until(e.deadline) > time.Minute {
+		t.Errorf("expected default query timeout, got deadline %v", e.deadline)
+	}
+	if len(events) != 1 || !events[0].Slow {
+		t.Errorf("expected hook to receive slow event, got %v", events)
+	}
+	if len(l.lines) != 1 {
+		t.Errorf("expected event to be logged with DB logger, got %q", l.lines)
+	}
+
+	// context deadline takes precedence over the default timeout
+	dctx, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+	doExecRaw(dctx, pdb.cfg, e, "SELECT 1")
+	if !e.ok || time.Until(e.deadline) < time.Minute {
+		t.Errorf("expected context deadline, got %v", e.deadline)
+	}
+}
+
+func TestTxTimeout(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		l := &captureLogger{}
+		pdb := &DB{DB: db.DB, Dialect: db.Dialect, cfg: newConfig([]Option{WithLogger(l), WithTxTimeout(50 * time.Millisecond)})}
+
+		tx := pdb.MustBegin(ctx)
+		ntx := tx.MustBegin(ctx)
+		if ntx.cfg != pdb.cfg {
+			t.Fatal("expected nested transaction to inherit DB configuration")
+		}
+		time.Sleep(100 * time.Millisecond)
+		if err := tx.Commit(); err == nil {
+			t.Fatal("expected commit of timed out transaction to fail")
+		}
+		if len(l.lines) == 0 {
+			t.Error("expected statements to be logged with DB logger")
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package prequel
+
+import "context"
+
+type sessionKey struct{}
+
+// WithRunner returns a copy of ctx which carries s, usually a Tx or Conn, so that code
+// called with the context can run queries using it with From instead of taking
+// a Runner parameter. Transactions and connections are only used with the DB they
+// belong to.
+func WithRunner(ctx context.Context, s Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// From returns the transaction or connection of db stored in ctx with WithRunner, or db
+// if there is none. Transactions and connections of other DBs are ignored.
+func From(ctx context.Context, db *DB) Session {
+	if s := sessionFor(ctx, db); s != nil {
+		return s
+	}
+	return db
+}
+
+// joinSession returns the session of db carried by ctx which s should use instead:
+// a transaction, or a connection if s is db itself. Otherwise s is returned.
+func joinSession(ctx context.Context, db *DB, s Session) Session {
+	switch cs := sessionFor(ctx, db).(type) {
+	case *Tx:
+		return cs
+	case *Conn:
+		if s == Session(db) {
+			return cs
+		}
+	}
+	return s
+}
+
+// sessionFor returns the session stored in ctx if it belongs to db.
+func sessionFor(ctx context.Context, db *DB) Session {
+	s, _ := ctx.Value(sessionKey{}).(Session)
+	switch s := s.(type) {
+	case *DB:
+		if s != db {
+			return nil
+		}
+	case *Tx:
+		if s.db != db {
+			return nil
+		}
+	case *Conn:
+		if s.db != db {
+			return nil
+		}
+	}
+	return s
+}
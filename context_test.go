@@ -0,0 +1,195 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+func TestFrom(t *testing.T) {
+	pdb := NewDB(nil, "postgres")
+	ctx := context.Background()
+
+	if s := From(ctx, pdb); s != pdb {
+		t.Errorf("expected From to fall back to DB, got %v", s)
+	}
+
+	tx := &Tx{db: pdb}
+	if s := From(WithRunner(ctx, tx), pdb); s != tx {
+		t.Errorf("expected From to return Tx, got %v", s)
+	}
+
+	conn := &Conn{db: pdb}
+	if s := From(WithRunner(ctx, conn), pdb); s != conn {
+		t.Errorf("expected From to return Conn, got %v", s)
+	}
+
+	// transactions and connections of other DBs are ignored
+	other := NewDB(nil, "postgres")
+	for _, s := range []Session{&Tx{db: other}, &Conn{db: other}, other} {
+		if s := From(WithRunner(ctx, s), pdb); s != pdb {
+			t.Errorf("expected From to ignore session of other DB, got %v", s)
+		}
+	}
+}
+
+func TestJoinSession(t *testing.T) {
+	pdb := NewDB(nil, "postgres")
+	ctx := context.Background()
+	conn := &Conn{db: pdb}
+	tx := &Tx{db: pdb}
+
+	examples := []struct {
+		ctx      context.Context
+		s        Session
+		expected Session
+	}{
+		{ctx, pdb, pdb},
+		{WithRunner(ctx, tx), pdb, tx},
+		{WithRunner(ctx, tx), conn, tx},
+		{WithRunner(ctx, conn), pdb, conn},
+		{WithRunner(ctx, &Conn{db: pdb}), conn, conn},
+		{WithRunner(ctx, &Tx{db: NewDB(nil, "postgres")}), pdb, pdb},
+	}
+	for i, x := range examples {
+		if s := joinSession(x.ctx, pdb, x.s); s != x.expected {
+			t.Errorf("example %d: expected %v, got %v", i, x.expected, s)
+		}
+	}
+}
+
+func TestRunInTxOptions(t *testing.T) {
+	pdb := NewDB(nil, "postgres")
+	tx := &Tx{db: pdb, opts: &sql.TxOptions{Isolation: sql.LevelSerializable}}
+	ntx := &Tx{db: pdb, parent: tx}
+
+	examples := []struct {
+		opts     *sql.TxOptions
+		expected bool
+	}{
+		{nil, true},
+		{&sql.TxOptions{}, true},
+		{&sql.TxOptions{Isolation: sql.LevelSerializable}, true},
+		{&sql.TxOptions{Isolation: sql.LevelReadCommitted}, false},
+		{&sql.TxOptions{ReadOnly: true}, false},
+	}
+	for i, x := range examples {
+		if ok := ntx.root().accepts(x.opts); ok != x.expected {
+			t.Errorf("example %d: expected %v, got %v", i, x.expected, ok)
+		}
+	}
+
+	ctx := WithRunner(context.Background(), ntx)
+	err := pdb.RunInTx(ctx, &RunTxOptions{TxOptions: &sql.TxOptions{ReadOnly: true}}, func(ctx context.Context, tx *Tx) error {
+		t.Fatal("expected fn not to be called")
+		return nil
+	})
+	if err == nil {
+		t.Error("expected joining with different options to fail")
+	}
+
+	err = ntx.RunInTx(ctx, &RunTxOptions{TxOptions: &sql.TxOptions{ReadOnly: true}}, func(ctx context.Context, tx *Tx) error {
+		t.Fatal("expected fn not to be called")
+		return nil
+	})
+	if err == nil {
+		t.Error("expected nested transaction with different options to fail")
+	}
+}
+
+func TestRunInTxAmbient(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		deleteUser := func(ctx context.Context, email string) error {
+			_, err := From(ctx, db).Exec(ctx, builder.Delete("users").Where("email = $1", email))
+			return err
+		}
+
+		failed := errors.New("failed")
+		err := db.RunInTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+			if From(ctx, db) != tx {
+				t.Fatal("expected context to carry the transaction")
+			}
+			if err := deleteUser(ctx, "user@example.com"); err != nil {
+				return err
+			}
+
+			// joins the transaction and rolls back only its own changes
+			err := db.RunInTx(ctx, nil, func(ctx context.Context, ntx *Tx) error {
+				if ntx.Tx != tx.Tx {
+					t.Fatal("expected nested transaction to join the ambient one")
+				}
+				if err := deleteUser(ctx, "john@mail.net"); err != nil {
+					return err
+				}
+				return failed
+			})
+			if err != failed {
+				t.Fatalf("expected err to be %v, got %v", failed, err)
+			}
+
+			// does not join the transaction of another DB
+			other := NewDB(db.DB.DB, "postgres")
+			return other.RunInTx(ctx, nil, func(ctx context.Context, otx *Tx) error {
+				if otx.Tx == tx.Tx || From(ctx, other) != otx {
+					t.Fatal("expected transaction of another DB to be independent")
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var n int
+		if err := db.GetRaw(ctx, &n, "SELECT count(*) FROM users"); err != nil {
+			t.Fatal(err)
+		}
+		if n != 2 {
+			t.Fatalf("expected %d records, got %d", 2, n)
+		}
+	})
+}
+
+func TestAmbientCopyAndChunks(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		count := func() int {
+			var n int
+			if err := db.GetRaw(ctx, &n, "SELECT count(*) FROM users"); err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+		before := count()
+
+		failed := errors.New("failed")
+		err := db.RunInTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+			columns := []string{"first_name", "last_name", "email"}
+			rows := [][]interface{}{{"Copy", "Ambient", "copy@example.com"}}
+			if _, err := db.CopyFrom(ctx, "users", columns, CopyFromRows(rows)); err != nil {
+				return err
+			}
+			b := builder.Insert("users").Columns(columns...).
+				Values("One", "Ambient", "one@example.com").
+				Values("Two", "Ambient", "two@example.com")
+			if _, err := db.InsertChunked(ctx, b, 1, nil); err != nil {
+				return err
+			}
+			return failed
+		})
+		if err != failed {
+			t.Fatalf("expected err to be %v, got %v", failed, err)
+		}
+
+		// rows were inserted in the ambient transaction and rolled back with it
+		if n := count(); n != before {
+			t.Fatalf("expected %d records, got %d", before, n)
+		}
+	})
+}
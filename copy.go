@@ -0,0 +1,284 @@
+package prequel
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/lib/pq"
+)
+
+// CopySource is a source of rows for CopyFrom.
+type CopySource interface {
+	// Next advances to the next row. It returns false when there are no more rows
+	// or an error occurred.
+	Next() bool
+	// Values returns values of the current row in CopyFrom columns order.
+	Values() ([]interface{}, error)
+	// Err returns the error, if any, encountered during iteration.
+	Err() error
+}
+
+// copyBinder is implemented by sources which need to know CopyFrom columns
+// before iteration. bind returns the columns to be used for COPY.
+type copyBinder interface {
+	bind(columns []string, m *reflectx.Mapper) ([]string, error)
+}
+
+// CopyFrom copies rows from src into table using COPY FROM STDIN in a new transaction
+// using this DB. It returns the number of copied rows. If ctx carries a transaction of
+// this DB (see WithRunner), rows are copied in a nested transaction of it.
+func (db *DB) CopyFrom(ctx context.Context, table string, columns []string, src CopySource) (int64, error) {
+	return copyIn(ctx, joinSession(ctx, db, db), table, columns, src)
+}
+
+// CopyFrom copies rows from src into table using COPY FROM STDIN in a nested transaction
+// of this transaction. It returns the number of copied rows. If the copy fails, rows
+// copied before the failure are rolled back to the savepoint of the nested transaction,
+// which is managed with the context of this transaction, so that it is rolled back even
+// if ctx is canceled.
+func (tx *Tx) CopyFrom(ctx context.Context, table string, columns []string, src CopySource) (int64, error) {
+	ntx, err := tx.Begin(tx.ctx)
+	if err != nil {
+		return 0, err
+	}
+	return commitCopy(ctx, ntx, table, columns, src)
+}
+
+// CopyFrom copies rows from src into table using COPY FROM STDIN in a new transaction
+// using this connection. It returns the number of copied rows.
+func (conn *Conn) CopyFrom(ctx context.Context, table string, columns []string, src CopySource) (int64, error) {
+	return copyIn(ctx, joinSession(ctx, conn.db, conn), table, columns, src)
+}
+
+// copyIn runs COPY in a new transaction begun with s, or in a nested transaction if s is a Tx.
+func copyIn(ctx context.Context, s Session, table string, columns []string, src CopySource) (int64, error) {
+	if tx, ok := s.(*Tx); ok {
+		return tx.CopyFrom(ctx, table, columns, src)
+	}
+	tx, err := s.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return commitCopy(ctx, tx, table, columns, src)
+}
+
+// commitCopy runs COPY using tx and commits it, or rolls it back on error.
+func commitCopy(ctx context.Context, tx *Tx, table string, columns []string, src CopySource) (int64, error) {
+	n, err := doCopyFrom(ctx, tx, table, columns, src)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// doCopyFrom streams rows from src to table with COPY FROM STDIN. Context is checked
+// before sending each row, so a cancelled copy stops mid-stream. Closing the statement
+// finishes COPY with the rows sent so far, so tx must be rolled back on error.
+func doCopyFrom(ctx context.Context, tx *Tx, table string, columns []string, src CopySource) (n int64, err error) {
+	start := time.Now()
+
+	if b, ok := src.(copyBinder); ok {
+		if columns, err = b.bind(columns, tx.Tx.Mapper); err != nil {
+			return 0, err
+		}
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("empty columns")
+	}
+
+	var query string
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		query = pq.CopyInSchema(table[:i], table[i+1:], columns...)
+	} else {
+		query = pq.CopyIn(table, columns...)
+	}
+	defer func() { tx.cfg.logQuery(ctx, start, KindCopy, query, nil, n, err) }()
+
+	stmt, err := tx.Tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for src.Next() {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		vals, err := src.Values()
+		if err != nil {
+			return n, err
+		}
+		if len(vals) != len(columns) {
+			return n, fmt.Errorf("invalid number of values, expected %d, got %d", len(columns), len(vals))
+		}
+		if _, err := stmt.ExecContext(ctx, vals...); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := src.Err(); err != nil {
+		return n, err
+	}
+
+	// flush buffered rows and finish COPY
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// CopyFromRows returns a CopySource which iterates over rows.
+func CopyFromRows(rows [][]interface{}) CopySource {
+	return &rowsSource{rows: rows, idx: -1}
+}
+
+type rowsSource struct {
+	rows [][]interface{}
+	idx  int
+}
+
+func (s *rowsSource) Next() bool {
+	s.idx++
+	return s.idx < len(s.rows)
+}
+
+func (s *rowsSource) Values() ([]interface{}, error) {
+	return s.rows[s.idx], nil
+}
+
+func (s *rowsSource) Err() error {
+	return nil
+}
+
+// CopyFromStructs returns a CopySource which iterates over slice, a slice of structs
+// or pointers to structs. Columns are mapped to struct fields the same way as for Select.
+func CopyFromStructs(slice interface{}) CopySource {
+	return &structsSource{v: reflect.ValueOf(slice), idx: -1}
+}
+
+type structsSource struct {
+	v      reflect.Value
+	idx    int
+	fields [][]int
+}
+
+func (s *structsSource) bind(columns []string, m *reflectx.Mapper) ([]string, error) {
+	if s.v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected slice of structs, got %v", s.v.Kind())
+	}
+	t := reflectx.Deref(s.v.Type().Elem())
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected slice of structs, got slice of %v", t)
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("empty columns")
+	}
+	tm := m.TypeMap(t)
+	s.fields = make([][]int, len(columns))
+	for i, c := range columns {
+		fi := tm.GetByPath(c)
+		if fi == nil {
+			return nil, fmt.Errorf("missing destination name %s in %v", c, t)
+		}
+		s.fields[i] = fi.Index
+	}
+	return columns, nil
+}
+
+func (s *structsSource) Next() bool {
+	s.idx++
+	return s.idx < s.v.Len()
+}
+
+func (s *structsSource) Values() ([]interface{}, error) {
+	v := reflect.Indirect(s.v.Index(s.idx))
+	if !v.IsValid() {
+		return nil, fmt.Errorf("nil element at index %d", s.idx)
+	}
+	vals := make([]interface{}, len(s.fields))
+	for i, idx := range s.fields {
+		vals[i] = reflectx.FieldByIndexesReadOnly(v, idx).Interface()
+	}
+	return vals, nil
+}
+
+func (s *structsSource) Err() error {
+	return nil
+}
+
+// CopyFromCSV returns a CopySource which reads CSV records from r. The first record
+// must be a header with column names: if CopyFrom columns are empty, header columns
+// are used, otherwise CSV fields are reordered to match columns. Empty fields are
+// copied as NULL.
+func CopyFromCSV(r io.Reader) CopySource {
+	return &csvSource{r: csv.NewReader(r)}
+}
+
+type csvSource struct {
+	r      *csv.Reader
+	order  []int
+	record []string
+	err    error
+}
+
+func (s *csvSource) bind(columns []string, m *reflectx.Mapper) ([]string, error) {
+	header, err := s.r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	pos := make(map[string]int, len(header))
+	for i, h := range header {
+		header[i] = strings.TrimSpace(h)
+		pos[header[i]] = i
+	}
+	if len(columns) == 0 {
+		columns = header
+	}
+
+	s.order = make([]int, len(columns))
+	for i, c := range columns {
+		p, ok := pos[c]
+		if !ok {
+			return nil, fmt.Errorf("missing column %s in CSV header", c)
+		}
+		s.order[i] = p
+	}
+	return columns, nil
+}
+
+func (s *csvSource) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	s.record, s.err = s.r.Read()
+	if s.err == io.EOF {
+		s.err = nil
+		return false
+	}
+	return s.err == nil
+}
+
+func (s *csvSource) Values() ([]interface{}, error) {
+	vals := make([]interface{}, len(s.order))
+	for i, p := range s.order {
+		if s.record[p] != "" {
+			vals[i] = s.record[p]
+		}
+	}
+	return vals, nil
+}
+
+func (s *csvSource) Err() error {
+	return s.err
+}
@@ -0,0 +1,176 @@
+package prequel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"syreclabs.com/go/prequel/builder"
+)
+
+func collectCopySource(t *testing.T, src CopySource, columns []string) ([]string, [][]interface{}) {
+	t.Helper()
+	if b, ok := src.(copyBinder); ok {
+		var err error
+		if columns, err = b.bind(columns, reflectx.NewMapperFunc("db", strings.ToLower)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var rows [][]interface{}
+	for src.Next() {
+		vals, err := src.Values()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, vals)
+	}
+	if err := src.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return columns, rows
+}
+
+func TestCopySources(t *testing.T) {
+	t.Run("Rows", func(t *testing.T) {
+		expected := [][]interface{}{{"First", "Last"}, {"Johnny", nil}}
+		_, rows := collectCopySource(t, CopyFromRows(expected), []string{"first_name", "last_name"})
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("expected rows to be %v, got %v", expected, rows)
+		}
+	})
+
+	t.Run("Structs", func(t *testing.T) {
+		users := []*User{
+			{FirstName: "First", LastName: "Last", Email: "user@example.com"},
+			{FirstName: "Johnny", LastName: "Doe", Email: "john@mail.net"},
+		}
+		expected := [][]interface{}{{"user@example.com", "First"}, {"john@mail.net", "Johnny"}}
+		_, rows := collectCopySource(t, CopyFromStructs(users), []string{"email", "first_name"})
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("expected rows to be %v, got %v", expected, rows)
+		}
+
+		src := CopyFromStructs(users).(copyBinder)
+		if _, err := src.bind([]string{"nickname"}, reflectx.NewMapperFunc("db", strings.ToLower)); err == nil {
+			t.Error("expected err not to be nil for unknown column")
+		}
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		data := "email,first_name,last_name\nuser@example.com,First,Last\njohn@mail.net,Johnny,\n"
+
+		columns, rows := collectCopySource(t, CopyFromCSV(strings.NewReader(data)), nil)
+		if !reflect.DeepEqual(columns, []string{"email", "first_name", "last_name"}) {
+			t.Errorf("expected header columns, got %v", columns)
+		}
+		if len(rows) != 2 || rows[1][2] != nil {
+			t.Errorf("expected 2 rows with NULL last value, got %v", rows)
+		}
+
+		expected := [][]interface{}{{"Last", "user@example.com"}, {nil, "john@mail.net"}}
+		_, rows = collectCopySource(t, CopyFromCSV(strings.NewReader(data)), []string{"last_name", "email"})
+		if !reflect.DeepEqual(rows, expected) {
+			t.Errorf("expected rows to be %v, got %v", expected, rows)
+		}
+	})
+}
+
+// cancelingSource cancels the copy context after n rows.
+type cancelingSource struct {
+	CopySource
+	n      int
+	cancel context.CancelFunc
+}
+
+func (s *cancelingSource) Next() bool {
+	if s.n == 0 {
+		s.cancel()
+	}
+	s.n--
+	return s.CopySource.Next()
+}
+
+func TestCopyFrom(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		t.Run("Structs", func(t *testing.T) {
+			users := []User{
+				{FirstName: "First", LastName: "Last", Email: "user@example.com"},
+				{FirstName: "Johnny", LastName: "Doe", Email: "john@mail.net"},
+			}
+			n, err := db.CopyFrom(ctx, "users", []string{"first_name", "last_name", "email"}, CopyFromStructs(users))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 2 {
+				t.Fatalf("expected %d rows, got %d", 2, n)
+			}
+		})
+
+		t.Run("CSV", func(t *testing.T) {
+			data := "email,first_name\njanie@email.com,Janie\n"
+			n, err := db.CopyFrom(ctx, "public.users", nil, CopyFromCSV(strings.NewReader(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Fatalf("expected %d rows, got %d", 1, n)
+			}
+		})
+
+		t.Run("Cancel", func(t *testing.T) {
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+			rows := [][]interface{}{{"Cancelled", "Cancelled", "cancelled@example.com"}}
+			if _, err := db.CopyFrom(cctx, "users", []string{"first_name", "last_name", "email"}, CopyFromRows(rows)); err == nil {
+				t.Fatal("expected err not to be nil")
+			}
+		})
+
+		t.Run("CancelMidStream", func(t *testing.T) {
+			rows := [][]interface{}{
+				{"One", "Cancelled", "one@example.com"},
+				{"Two", "Cancelled", "two@example.com"},
+				{"Three", "Cancelled", "three@example.com"},
+			}
+			columns := []string{"first_name", "last_name", "email"}
+
+			cctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			_, err := db.CopyFrom(cctx, "users", columns, &cancelingSource{CopyFromRows(rows), 2, cancel})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected err to be %v, got %v", context.Canceled, err)
+			}
+
+			// rows sent before cancellation are rolled back in the caller's transaction too
+			tx := db.MustBegin(ctx)
+			defer tx.Rollback()
+			cctx, cancel = context.WithCancel(ctx)
+			defer cancel()
+			_, err = tx.CopyFrom(cctx, "users", columns, &cancelingSource{CopyFromRows(rows), 2, cancel})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected err to be %v, got %v", context.Canceled, err)
+			}
+			var n int
+			if err := tx.GetRaw(ctx, &n, "SELECT count(*) FROM users WHERE last_name = 'Cancelled'"); err != nil {
+				t.Fatalf("expected transaction to be usable, got %v", err)
+			}
+			if n != 0 {
+				t.Errorf("expected no copied rows, got %d", n)
+			}
+			if err := tx.Commit(); err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		var count int
+		if err := db.Get(ctx, builder.Select("count(*)").From("users"), &count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 3 {
+			t.Fatalf("expected %d records, got %d", 3, count)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package prequel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"syreclabs.com/go/prequel/builder"
+)
+
+// CursorOptions holds the options used in Cursor.
+type CursorOptions struct {
+	// Scroll allows fetching rows backwards (DECLARE ... SCROLL CURSOR).
+	Scroll bool
+	// WithHold keeps the cursor open after the transaction that created it
+	// commits (DECLARE ... CURSOR WITH HOLD).
+	WithHold bool
+}
+
+// Cursor is a server-side cursor created with Tx.Cursor or Conn.Cursor.
+// It allows walking huge result sets keeping only fetched rows in memory.
+type Cursor struct {
+	r    Runner
+	name string
+}
+
+// Cursor declares a server-side cursor for the query built by b in this transaction.
+// opts may be nil.
+func (tx *Tx) Cursor(ctx context.Context, name string, b builder.Builder, opts *CursorOptions) (*Cursor, error) {
+	return declareCursor(ctx, tx, name, b, opts)
+}
+
+// Cursor declares a server-side cursor for the query built by b using this connection.
+// As the connection is not in a transaction, opts.WithHold must be set.
+func (conn *Conn) Cursor(ctx context.Context, name string, b builder.Builder, opts *CursorOptions) (*Cursor, error) {
+	if opts == nil || !opts.WithHold {
+		return nil, errors.New("cursor outside of transaction requires WithHold")
+	}
+	return declareCursor(ctx, conn, name, b, opts)
+}
+
+func declareCursor(ctx context.Context, r Runner, name string, b builder.Builder, opts *CursorOptions) (*Cursor, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("empty cursor name")
+	}
+	sql, params, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cursor{r: r, name: pq.QuoteIdentifier(name)}
+
+	q := "DECLARE " + c.name
+	if opts != nil && opts.Scroll {
+		q += " SCROLL"
+	}
+	q += " CURSOR"
+	if opts != nil && opts.WithHold {
+		q += " WITH HOLD"
+	}
+	q += " FOR "
+
+	// the cursor query is appended as-is, so its placeholders keep their numbers
+	if _, err := r.Exec(ctx, builder.SQL(q+sql, params...)); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Fetch fetches up to n rows into dest, which must be a pointer to a slice. Existing
+// dest elements are discarded. Negative n fetches backwards, which requires Scroll.
+func (c *Cursor) Fetch(ctx context.Context, n int, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return errors.New("dest must be a pointer to a slice")
+	}
+	v.Elem().SetLen(0)
+	return c.r.SelectRaw(ctx, dest, "FETCH "+direction(n)+" FROM "+c.name)
+}
+
+// Move repositions the cursor by n rows without fetching them and returns the number
+// of rows it moved over. Negative n moves backwards, which requires Scroll.
+func (c *Cursor) Move(ctx context.Context, n int) (int64, error) {
+	res, err := c.r.ExecRaw(ctx, "MOVE "+direction(n)+" FROM "+c.name)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Close closes the cursor.
+func (c *Cursor) Close(ctx context.Context) error {
+	_, err := c.r.ExecRaw(ctx, "CLOSE "+c.name)
+	return err
+}
+
+// EachBatch fetches rows n at a time into dest, which must be a pointer to a slice,
+// and calls fn after each fetch until the cursor is exhausted or fn returns an error.
+func (c *Cursor) EachBatch(ctx context.Context, n int, dest interface{}, fn func() error) error {
+	if n < 1 {
+		return fmt.Errorf("invalid batch size: %d", n)
+	}
+	for {
+		if err := c.Fetch(ctx, n, dest); err != nil {
+			return err
+		}
+		count := reflect.ValueOf(dest).Elem().Len()
+		if count == 0 {
+			return nil
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+		if count < n {
+			return nil
+		}
+	}
+}
+
+func direction(n int) string {
+	if n < 0 {
+		return "BACKWARD " + strconv.Itoa(-n)
+	}
+	return "FORWARD " + strconv.Itoa(n)
+}
@@ -0,0 +1,96 @@
+package prequel
+
+import (
+	"context"
+	"testing"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+func TestCursor(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		b := builder.
+			Select("id", "first_name", "last_name", "email").
+			From("users").
+			Where("id > $1", 0).
+			OrderBy("id")
+
+		t.Run("Fetch", func(t *testing.T) {
+			tx := db.MustBegin(ctx)
+			defer tx.Rollback()
+
+			c, err := tx.Cursor(ctx, "users_cursor", b, &CursorOptions{Scroll: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close(ctx)
+
+			var users []*User
+			if err := c.Fetch(ctx, 2, &users); err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 2 {
+				t.Fatalf("expected %d records, got %d", 2, len(users))
+			}
+
+			moved, err := c.Move(ctx, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved != 1 {
+				t.Fatalf("expected to move over %d rows, got %d", 1, moved)
+			}
+
+			if err := c.Fetch(ctx, 5, &users); err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 2 || users[0].Email != "john@mail.net" {
+				t.Fatalf("expected %d records starting with %q, got %v", 2, "john@mail.net", users)
+			}
+		})
+
+		t.Run("EachBatch", func(t *testing.T) {
+			tx := db.MustBegin(ctx)
+			defer tx.Rollback()
+
+			c, err := tx.Cursor(ctx, "users_batches", b, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close(ctx)
+
+			var users []*User
+			var batches, total int
+			err = c.EachBatch(ctx, 2, &users, func() error {
+				batches++
+				total += len(users)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if batches != 2 || total != 3 {
+				t.Fatalf("expected %d batches with %d records, got %d with %d", 2, 3, batches, total)
+			}
+		})
+
+		t.Run("ConnWithoutHold", func(t *testing.T) {
+			conn := db.MustConn(ctx)
+			defer conn.Close()
+
+			if _, err := conn.Cursor(ctx, "users_conn", b, nil); err == nil {
+				t.Fatal("expected err not to be nil")
+			}
+		})
+	})
+}
+
+func TestCursorDirection(t *testing.T) {
+	for n, expected := range map[int]string{0: "FORWARD 0", 10: "FORWARD 10", -3: "BACKWARD 3"} {
+		if d := direction(n); d != expected {
+			t.Errorf("expected direction %q, got %q", expected, d)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package prequel
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"syreclabs.com/go/prequel/builder"
+)
+
+// ExportFormat is an output format used by Export.
+type ExportFormat int
+
+const (
+	// ExportCSV writes a header with column names followed by CSV records.
+	ExportCSV ExportFormat = iota
+	// ExportJSONLines writes one JSON object per row.
+	ExportJSONLines
+	// ExportCopyText writes rows in PostgreSQL COPY TO STDOUT text format:
+	// tab separated values with \N for NULL.
+	ExportCopyText
+)
+
+func (f ExportFormat) String() string {
+	switch f {
+	case ExportCSV:
+		return "csv"
+	case ExportJSONLines:
+		return "jsonl"
+	case ExportCopyText:
+		return "copy"
+	}
+	return "ExportFormat(" + strconv.Itoa(int(f)) + ")"
+}
+
+// Export runs the query built by b using this DB and streams resulting rows to w
+// in the given format. Rows are written as they are received, so the whole
+// result set is never held in memory. It returns the number of exported rows.
+func (db *DB) Export(ctx context.Context, b builder.Builder, w io.Writer, format ExportFormat) (int64, error) {
+	return doExport(ctx, db.cfg, db.DB, builder.WithDialect(db.Dialect, b), w, format)
+}
+
+// Export runs the query built by b using this transaction and streams resulting rows to w.
+// See DB.Export for details.
+func (tx *Tx) Export(ctx context.Context, b builder.Builder, w io.Writer, format ExportFormat) (int64, error) {
+	return doExport(ctx, tx.cfg, tx.Tx, builder.WithDialect(tx.dialect, b), w, format)
+}
+
+// Export runs the query built by b using this connection and streams resulting rows to w.
+// See DB.Export for details.
+func (conn *Conn) Export(ctx context.Context, b builder.Builder, w io.Writer, format ExportFormat) (int64, error) {
+	return doExport(ctx, conn.cfg, conn.Conn, builder.WithDialect(conn.dialect, b), w, format)
+}
+
+// doExport builds the query using the provided builder, executes it with queryer and
+// writes each row to w using the format encoder.
+func doExport(ctx context.Context, cfg *config, q sqlx.QueryerContext, b builder.Builder, w io.Writer, format ExportFormat) (int64, error) {
+	rows, err := doQuery(ctx, cfg, q, b)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	names := make([]string, len(columns))
+	types := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name()
+		types[i] = c.DatabaseTypeName()
+	}
+
+	bw := bufio.NewWriter(w)
+	enc, err := newExportEncoder(format, bw, names, types)
+	if err != nil {
+		return 0, err
+	}
+
+	vals := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return rows.Count(), err
+		}
+		if err := enc.writeRow(vals); err != nil {
+			return rows.Count(), err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return rows.Count(), err
+	}
+	if err := enc.flush(); err != nil {
+		return rows.Count(), err
+	}
+	return rows.Count(), bw.Flush()
+}
+
+type exportEncoder interface {
+	writeRow(vals []interface{}) error
+	flush() error
+}
+
+// newExportEncoder returns an encoder for columns with the given names and database
+// type names. CSV header is written immediately.
+func newExportEncoder(format ExportFormat, w *bufio.Writer, names, types []string) (exportEncoder, error) {
+	switch format {
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(names); err != nil {
+			return nil, err
+		}
+		return &csvEncoder{cw, types, make([]string, len(names))}, nil
+	case ExportJSONLines:
+		keys := make([][]byte, len(names))
+		for i, name := range names {
+			k, err := json.Marshal(name)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = k
+		}
+		return &jsonLinesEncoder{w, keys, types}, nil
+	case ExportCopyText:
+		return &copyTextEncoder{w, types}, nil
+	}
+	return nil, fmt.Errorf("unsupported export format: %v", format)
+}
+
+// formatText returns text representation of a scanned value of a column with the
+// given database type. bytea values are hex-encoded like PostgreSQL does.
+func formatText(v interface{}, typ string) string {
+	switch v := v.(type) {
+	case []byte:
+		if typ == "BYTEA" {
+			return `\x` + hex.EncodeToString(v)
+		}
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case bool:
+		if v {
+			return "t"
+		}
+		return "f"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	types  []string
+	record []string
+}
+
+func (e *csvEncoder) writeRow(vals []interface{}) error {
+	for i, v := range vals {
+		if v == nil {
+			e.record[i] = ""
+		} else {
+			e.record[i] = formatText(v, e.types[i])
+		}
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonLinesEncoder struct {
+	w     *bufio.Writer
+	keys  [][]byte
+	types []string
+}
+
+func (e *jsonLinesEncoder) writeRow(vals []interface{}) error {
+	e.w.WriteByte('{')
+	for i, v := range vals {
+		if i > 0 {
+			e.w.WriteByte(',')
+		}
+		e.w.Write(e.keys[i])
+		e.w.WriteByte(':')
+
+		var js []byte
+		var err error
+		switch vv := v.(type) {
+		case []byte:
+			switch e.types[i] {
+			case "JSON", "JSONB":
+				js = vv
+			case "BYTEA":
+				js, err = json.Marshal(vv) // base64
+			default:
+				js, err = json.Marshal(string(vv))
+			}
+		default:
+			js, err = json.Marshal(v)
+		}
+		if err != nil {
+			return err
+		}
+		e.w.Write(js)
+	}
+	e.w.WriteByte('}')
+	return e.w.WriteByte('\n')
+}
+
+func (e *jsonLinesEncoder) flush() error {
+	return nil
+}
+
+var copyTextReplacer = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+type copyTextEncoder struct {
+	w     *bufio.Writer
+	types []string
+}
+
+func (e *copyTextEncoder) writeRow(vals []interface{}) error {
+	for i, v := range vals {
+		if i > 0 {
+			e.w.WriteByte('\t')
+		}
+		if v == nil {
+			e.w.WriteString(`\N`)
+			continue
+		}
+		if t, ok := v.(time.Time); ok {
+			e.w.WriteString(t.Format("2006-01-02 15:04:05.999999Z07:00"))
+			continue
+		}
+		copyTextReplacer.WriteString(e.w, formatText(v, e.types[i]))
+	}
+	return e.w.WriteByte('\n')
+}
+
+func (e *copyTextEncoder) flush() error {
+	return nil
+}
@@ -0,0 +1,91 @@
+package prequel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+func TestExportEncoders(t *testing.T) {
+	names := []string{"id", "name", "data", "raw", "created_at", "active"}
+	types := []string{"INT4", "TEXT", "JSONB", "BYTEA", "TIMESTAMPTZ", "BOOL"}
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	rows := [][]interface{}{
+		{int64(1), "tab\there", []byte(`{"a":1}`), []byte{0xde, 0xad}, ts, true},
+		{int64(2), nil, nil, nil, nil, false},
+	}
+
+	examples := []struct {
+		format   ExportFormat
+		expected string
+	}{
+		{
+			ExportCSV,
+			"id,name,data,raw,created_at,active\n" +
+				"1,tab\there,\"{\"\"a\"\":1}\",\\xdead,2020-01-02T03:04:05.6Z,t\n" +
+				"2,,,,,f\n",
+		},
+		{
+			ExportJSONLines,
+			`{"id":1,"name":"tab\there","data":{"a":1},"raw":"3q0=","created_at":"2020-01-02T03:04:05.6Z","active":true}` + "\n" +
+				`{"id":2,"name":null,"data":null,"raw":null,"created_at":null,"active":false}` + "\n",
+		},
+		{
+			ExportCopyText,
+			"1\ttab\\there\t{\"a\":1}\t\\\\xdead\t2020-01-02 03:04:05.6Z\tt\n" +
+				"2\t\\N\t\\N\t\\N\t\\N\tf\n",
+		},
+	}
+
+	for _, x := range examples {
+		t.Run(x.format.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			enc, err := newExportEncoder(x.format, w, names, types)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range rows {
+				if err := enc.writeRow(row); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := enc.flush(); err != nil {
+				t.Fatal(err)
+			}
+			w.Flush()
+			if buf.String() != x.expected {
+				t.Errorf("expected output\n%s\ngot\n%s", x.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		b := builder.
+			Select("first_name", "last_name", "email").
+			From("users").
+			OrderBy("id")
+
+		var buf bytes.Buffer
+		n, err := db.Export(ctx, b, &buf, ExportCSV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 {
+			t.Fatalf("expected %d rows, got %d", 3, n)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 4 || lines[0] != "first_name,last_name,email" {
+			t.Fatalf("unexpected CSV output %q", buf.String())
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+// Transaction lifecycle kinds of intercepted calls.
+const (
+	KindBegin    QueryKind = "begin"
+	KindCommit   QueryKind = "commit"
+	KindRollback QueryKind = "rollback"
+)
+
+// Call describes an intercepted call of Select, Get, Exec, Query (including their Raw and
+// Must* variants, Each and Export), Begin, Commit or Rollback. Interceptors may change SQL
+// and Params before calling next, and read Rows and Result after it returns. Rows of Query
+// is -1, as its rows are read after next returns.
+type Call struct {
+	Kind QueryKind
+	// Builder is the builder of the statement, or nil for Raw methods and transactions.
+	Builder builder.Builder
+	SQL     string
+	Params  []interface{}
+	// Dest is the destination of Select and Get.
+	Dest interface{}
+	// TxOptions are the options of Begin.
+	TxOptions *sql.TxOptions
+
+	// Rows is the number of rows returned or affected, or -1 if it is unknown.
+	Rows int64
+	// Result is the result of Exec. Interceptors which short-circuit Exec must set it.
+	Result sql.Result
+}
+
+// Handler runs the call, or passes it to the next interceptor.
+type Handler func(ctx context.Context, call *Call) error
+
+// Interceptor wraps calls made using DB and its transactions and connections, e.g. to
+// trace or audit them. An interceptor can pass a modified context to next, return an error
+// without calling next to fail the call, or return nil to short-circuit it, in which case
+// Dest of Select and Get is left to the interceptor, and Exec fails unless the interceptor
+// sets Result. Query, Begin, Commit and Rollback can not be short-circuited, and the transaction
+// remains active if Commit or Rollback fails before next is called. Commit and Rollback of
+// nested transactions are not intercepted, as they use savepoints. Calls whose Builder
+// fails to build have empty SQL, and next returns the build error.
+type Interceptor func(ctx context.Context, call *Call, next Handler) error
+
+// WithInterceptors adds interceptors to DB. The first interceptor is the outermost one.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *config) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// buildCall builds SQL and Params of call using its Builder. A build error is passed to
+// interceptors as the result of next, and is returned even if they ignore it.
+func buildCall(ctx context.Context, cfg *config, call *Call) error {
+	var err error
+	call.SQL, call.Params, err = call.Builder.Build()
+	if err == nil {
+		return nil
+	}
+	call.Rows = -1
+	if ierr := cfg.intercept(ctx, call, func(ctx context.Context, call *Call) error { return err }); ierr != nil {
+		return ierr
+	}
+	return err
+}
+
+func errShortCircuited(kind QueryKind) error {
+	return fmt.Errorf("%s can not be short-circuited by interceptor", kind)
+}
+
+// intercept runs h wrapped in interceptors of c. It is safe to call on nil.
+func (c *config) intercept(ctx context.Context, call *Call, h Handler) error {
+	if c == nil {
+		return h(ctx, call)
+	}
+	next := h
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		ic, n := c.interceptors[i], next
+		next = func(ctx context.Context, call *Call) error {
+			return ic(ctx, call, n)
+		}
+	}
+	return next(ctx, call)
+}
@@ -0,0 +1,166 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"syreclabs.com/go/prequel/builder"
+)
+
+type ctxKey string
+
+type recordingExecer struct {
+	queries []string
+	values  []interface{}
+}
+
+func (e *recordingExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.queries = append(e.queries, query)
+	e.values = append(e.values, ctx.Value(ctxKey("trace")))
+	return driver.RowsAffected(1), nil
+}
+
+type failingBeginner struct{ err error }
+
+func (b failingBeginner) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error) {
+	return nil, b.err
+}
+
+func TestInterceptors(t *testing.T) {
+	var calls []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, call *Call, next Handler) error {
+			calls = append(calls, fmt.Sprintf("%s before %s", name, call.Kind))
+			err := next(ctx, call)
+			calls = append(calls, fmt.Sprintf("%s after %d rows", name, call.Rows))
+			return err
+		}
+	}
+	trace := func(ctx context.Context, call *Call, next Handler) error {
+		if call.Builder == nil {
+			return errors.New("expected builder")
+		}
+		call.SQL = "/* traced */ " + call.SQL
+		return next(context.WithValue(ctx, ctxKey("trace"), "id"), call)
+	}
+	cfg := newConfig([]Option{WithLogger(&captureLogger{}), WithInterceptors(record("outer"), record("inner"), trace)})
+
+	ctx := context.Background()
+	e := &recordingExecer{}
+	res, err := doExec(ctx, cfg, e, builder.Delete("users").Where("id = $1", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("expected %d affected rows, got %d", 1, n)
+	}
+	expected := []string{"outer before exec", "inner before exec", "inner after 1 rows", "outer after 1 rows"}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+	if len(e.queries) != 1 || e.queries[0] != "/* traced */ DELETE FROM users WHERE (id = $1)" || e.values[0] != "id" {
+		t.Errorf("expected modified query and context, got %q %v", e.queries, e.values)
+	}
+
+	// short-circuit
+	cached := driver.RowsAffected(5)
+	cfg = newConfig([]Option{WithInterceptors(func(ctx context.Context, call *Call, next Handler) error {
+		call.Result = cached
+		return nil
+	})})
+	e = &recordingExecer{}
+	if res, err := doExecRaw(ctx, cfg, e, "DELETE FROM users"); err != nil || res != cached {
+		t.Errorf("expected short-circuited result, got %v, %v", res, err)
+	}
+	if len(e.queries) != 0 {
+		t.Errorf("expected statement not to be executed, got %q", e.queries)
+	}
+	if _, err := beginTx(ctx, cfg, failingBeginner{}, nil); err == nil {
+		t.Error("expected short-circuited begin to fail")
+	}
+	cfg = newConfig([]Option{WithInterceptors(func(ctx context.Context, call *Call, next Handler) error {
+		return nil
+	})})
+	if res, err := doExecRaw(ctx, cfg, e, "DELETE FROM users"); err == nil || res != nil {
+		t.Errorf("expected exec short-circuited without result to fail, got %v, %v", res, err)
+	}
+	if rows, err := doQueryRaw(ctx, cfg, nil, "SELECT * FROM users"); err == nil || rows != nil {
+		t.Errorf("expected short-circuited query to fail, got %v, %v", rows, err)
+	}
+
+	// build failure
+	var seen []error
+	cfg = newConfig([]Option{WithInterceptors(func(ctx context.Context, call *Call, next Handler) error {
+		err := next(ctx, call)
+		seen = append(seen, err)
+		return nil // ignored build errors are still returned
+	})})
+	invalid := builder.Delete("users").Where("id = $2", 1)
+	if _, err := doExec(ctx, cfg, e, invalid); err == nil {
+		t.Error("expected build to fail")
+	}
+	var users []User
+	if err := doSelect(ctx, cfg, nil, builder.Select("*").From("users").Where("id = $2", 1), &users); err == nil {
+		t.Error("expected build to fail")
+	}
+	if _, err := doQuery(ctx, cfg, nil, builder.Select("*").From("users").Where("id = $2", 1)); err == nil {
+		t.Error("expected build to fail")
+	}
+	if len(seen) != 3 || seen[0] == nil || seen[1] == nil || seen[2] == nil {
+		t.Errorf("expected interceptor to see build errors, got %v", seen)
+	}
+	if len(e.queries) != 0 {
+		t.Errorf("expected statement not to be executed, got %q", e.queries)
+	}
+
+	// fail
+	failed := errors.New("budget exceeded")
+	var kinds []QueryKind
+	cfg = newConfig([]Option{WithInterceptors(func(ctx context.Context, call *Call, next Handler) error {
+		kinds = append(kinds, call.Kind)
+		return failed
+	})})
+	if _, err := doExecRaw(ctx, cfg, e, "DELETE FROM users"); err != failed {
+		t.Errorf("expected err to be %v, got %v", failed, err)
+	}
+	if _, err := doQueryRaw(ctx, cfg, nil, "SELECT * FROM users"); err != failed {
+		t.Errorf("expected err to be %v, got %v", failed, err)
+	}
+	if _, err := beginTx(ctx, cfg, failingBeginner{}, nil); err != failed {
+		t.Errorf("expected err to be %v, got %v", failed, err)
+	}
+	expectedKinds := []QueryKind{KindExec, KindQuery, KindBegin}
+	if fmt.Sprint(kinds) != fmt.Sprint(expectedKinds) {
+		t.Errorf("expected calls %v, got %v", expectedKinds, kinds)
+	}
+}
+
+func TestTxInterceptors(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		var kinds []QueryKind
+		cfg := newConfig([]Option{WithInterceptors(func(ctx context.Context, call *Call, next Handler) error {
+			kinds = append(kinds, call.Kind)
+			return next(ctx, call)
+		})})
+		pdb := &DB{DB: db.DB, Dialect: db.Dialect, cfg: cfg}
+
+		tx := pdb.MustBegin(ctx)
+		ntx := tx.MustBegin(ctx)
+		ntx.MustExecRaw(ctx, "DELETE FROM users")
+		if err := ntx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		expected := []QueryKind{KindBegin, KindExec, KindCommit}
+		if fmt.Sprint(kinds) != fmt.Sprint(expected) {
+			t.Errorf("expected calls %v, got %v", expected, kinds)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package prequel
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OpenTransaction describes a transaction or connection which has not been closed yet.
+type OpenTransaction struct {
+	// Kind is "Tx" or "Conn".
+	Kind    string
+	Created time.Time
+	// Stack is the stack trace of the goroutine which created the transaction or connection.
+	Stack string
+}
+
+// Age returns how long the transaction or connection has been open.
+func (t OpenTransaction) Age() time.Duration {
+	return time.Since(t.Created)
+}
+
+// DetectLeaks enables leak detection for transactions and connections created after
+// the call using this DB. It records the creation stack of each Tx and Conn and reports
+// them through the DB logger if they are still open after maxAge (zero disables this
+// check), or if they are garbage collected without Commit, Rollback or Close.
+// Leak detection is meant for debugging, as capturing stacks is expensive.
+func (db *DB) DetectLeaks(maxAge time.Duration) {
+	l := &leakTracker{cfg: db.cfg, maxAge: maxAge, open: make(map[*tracked]struct{})}
+	db.leaksMu.Lock()
+	db.leaks = l
+	db.leaksMu.Unlock()
+}
+
+// tracker returns the tracker set with DetectLeaks, or nil.
+func (db *DB) tracker() *leakTracker {
+	db.leaksMu.RLock()
+	defer db.leaksMu.RUnlock()
+	return db.leaks
+}
+
+// OpenTransactions returns transactions and connections which are currently open,
+// oldest first. It returns nil unless leak detection is enabled with DetectLeaks.
+func (db *DB) OpenTransactions() []OpenTransaction {
+	l := db.tracker()
+	if l == nil {
+		return nil
+	}
+	return l.snapshot()
+}
+
+type leakTracker struct {
+	cfg    *config
+	maxAge time.Duration
+
+	mu   sync.Mutex
+	open map[*tracked]struct{}
+}
+
+type tracked struct {
+	t      *leakTracker
+	info   OpenTransaction
+	timer  *time.Timer
+	closed bool
+}
+
+// track starts tracking obj, which must be a pointer to Tx or Conn. The returned value
+// must not be referenced by obj's finalizer, so obj can be garbage collected.
+func (l *leakTracker) track(kind string, obj interface{}) *tracked {
+	if l == nil {
+		return nil
+	}
+	buf := make([]byte, 8192)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	tr := &tracked{t: l, info: OpenTransaction{Kind: kind, Created: time.Now(), Stack: string(buf)}}
+	l.mu.Lock()
+	l.open[tr] = struct{}{}
+	if l.maxAge > 0 {
+		tr.timer = time.AfterFunc(l.maxAge, func() { tr.report("still open after "+l.maxAge.String(), false) })
+	}
+	l.mu.Unlock()
+
+	runtime.SetFinalizer(obj, func(interface{}) { tr.report("garbage collected without being closed", true) })
+	return tr
+}
+
+// done stops tracking. It is safe to call on nil.
+func (tr *tracked) done() {
+	if tr == nil {
+		return
+	}
+	tr.t.mu.Lock()
+	defer tr.t.mu.Unlock()
+	if tr.closed {
+		return
+	}
+	tr.closed = true
+	delete(tr.t.open, tr)
+	if tr.timer != nil {
+		tr.timer.Stop()
+	}
+}
+
+// report logs the leak. Collected objects can never be closed, so they stop being tracked.
+func (tr *tracked) report(reason string, collected bool) {
+	tr.t.mu.Lock()
+	closed := tr.closed
+	if collected && !closed {
+		tr.closed = true
+		delete(tr.t.open, tr)
+		if tr.timer != nil {
+			tr.timer.Stop()
+		}
+	}
+	tr.t.mu.Unlock()
+	if closed {
+		return
+	}
+	tr.t.cfg.log().Printf("LEAK %s %s, created at %v:\n%s", tr.info.Kind, reason, tr.info.Created.Format(time.RFC3339), tr.info.Stack)
+}
+
+func (l *leakTracker) snapshot() []OpenTransaction {
+	l.mu.Lock()
+	res := make([]OpenTransaction, 0, len(l.open))
+	for tr := range l.open {
+		res = append(res, tr.info)
+	}
+	l.mu.Unlock()
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Created.Before(res[j].Created) })
+	return res
+}
@@ -0,0 +1,136 @@
+package prequel
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type captureLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *captureLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *captureLogger) SetLevel(lvl int) {}
+
+func (l *captureLogger) leaks() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var res []string
+	for _, s := range l.lines {
+		if strings.HasPrefix(s, "LEAK ") {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func TestLeakTracker(t *testing.T) {
+	l := &captureLogger{}
+	pdb := NewDB(nil, "postgres", WithLogger(l))
+	if pdb.OpenTransactions() != nil {
+		t.Fatal("expected no open transactions without leak detection")
+	}
+	pdb.DetectLeaks(20 * time.Millisecond)
+
+	closed := pdb.leaks.track("Tx", &Tx{})
+	leaked := pdb.leaks.track("Conn", &Conn{})
+	closed.done()
+	closed.done()
+
+	open := pdb.OpenTransactions()
+	if len(open) != 1 || open[0].Kind != "Conn" {
+		t.Fatalf("expected single open Conn, got %+v", open)
+	}
+	if !strings.Contains(open[0].Stack, "TestLeakTracker") {
+		t.Errorf("expected creation stack to contain test function, got %s", open[0].Stack)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(l.leaks()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	leaks := l.leaks()
+	if len(leaks) != 1 || !strings.HasPrefix(leaks[0], "LEAK Conn still open after 20ms") {
+		t.Fatalf("expected Conn leak to be reported, got %v", leaks)
+	}
+
+	// reported objects remain open until closed
+	if len(pdb.OpenTransactions()) != 1 {
+		t.Fatal("expected leaked Conn to remain open")
+	}
+	leaked.done()
+	if len(pdb.OpenTransactions()) != 0 {
+		t.Fatal("expected no open transactions")
+	}
+}
+
+func TestLeakTrackerFinalizer(t *testing.T) {
+	l := &captureLogger{}
+	pdb := NewDB(nil, "postgres", WithLogger(l))
+	pdb.DetectLeaks(0)
+
+	func() {
+		tx := &Tx{}
+		tx.leak = pdb.leaks.track("Tx", tx)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(l.leaks()) == 0 && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	leaks := l.leaks()
+	if len(leaks) != 1 || !strings.HasPrefix(leaks[0], "LEAK Tx garbage collected without being closed") {
+		t.Fatalf("expected Tx leak to be reported, got %v", leaks)
+	}
+	if len(pdb.OpenTransactions()) != 0 {
+		t.Fatal("expected collected Tx not to be open")
+	}
+}
+
+func TestDetectLeaksConcurrent(t *testing.T) {
+	pdb := NewDB(nil, "postgres", WithLogger(&captureLogger{}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		pdb.DetectLeaks(0)
+	}()
+	pdb.tracker().track("Tx", &Tx{}).done()
+	pdb.OpenTransactions()
+	wg.Wait()
+
+	if pdb.OpenTransactions() == nil {
+		t.Fatal("expected leak detection to be enabled")
+	}
+}
+
+func TestOpenTransactions(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		pdb := &DB{DB: db.DB, Dialect: db.Dialect, cfg: db.cfg}
+		pdb.DetectLeaks(0)
+
+		tx := pdb.MustBegin(ctx)
+		conn := pdb.MustConn(ctx)
+		if open := pdb.OpenTransactions(); len(open) != 2 || open[0].Kind != "Tx" || open[1].Kind != "Conn" {
+			t.Fatalf("expected Tx and Conn to be open, got %+v", open)
+		}
+		tx.Rollback()
+		conn.Close()
+		if open := pdb.OpenTransactions(); len(open) != 0 {
+			t.Fatalf("expected no open transactions, got %+v", open)
+		}
+	})
+}
@@ -1,6 +1,16 @@
 package prequel
 
-import "syreclabs.com/go/loggie"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"syreclabs.com/go/loggie"
+	"syreclabs.com/go/prequel/builder"
+)
 
 // Logger is a logging interface user by prequel.
 type Logger interface {
@@ -8,8 +18,77 @@ type Logger interface {
 	SetLevel(lvl int)
 }
 
+// QueryLogger is implemented by loggers which receive structured query events. If the
+// Logger set with WithLogger or SetLogger implements QueryLogger, LogQuery is called for each executed
+// statement. Otherwise the event is formatted with QueryEvent.String and logged with Printf.
+type QueryLogger interface {
+	LogQuery(ctx context.Context, e *QueryEvent)
+}
+
+// QueryKind is the kind of the logged statement.
+type QueryKind string
+
+const (
+	KindSelect  QueryKind = "select"  // Select and SelectRaw
+	KindGet     QueryKind = "get"     // Get and GetRaw
+	KindExec    QueryKind = "exec"    // Exec, ExecRaw and their Must* variants
+	KindQuery   QueryKind = "query"   // Query, QueryRaw and Each
+	KindCopy    QueryKind = "copy"    // CopyFrom and InsertChunked
+	KindPrepare QueryKind = "prepare" // Prepare and cached statements
+)
+
+// QueryEvent describes an executed statement.
+type QueryEvent struct {
+	// Kind is the method which ran the statement, e.g. KindExec, and Statement is
+	// the kind of the statement itself, e.g. builder.KindInsert.
+	Kind      QueryKind
+	Statement builder.Kind
+	SQL       string
+	Params    []interface{}
+	// Start is the time the statement started, including building it, and Duration is
+	// the time until it completed, excluding plan capture.
+	Start    time.Time
+	Duration time.Duration
+	// Rows is the number of rows affected by the statement or returned by the query,
+	// or -1 if it is unknown.
+	Rows int64
+	Err  error
+	// Slow is true if the statement took longer than the threshold set with
+	// WithSlowQueryThreshold.
+	Slow bool
+	// Plan is the JSON plan of a slow statement, if captured (see SlowQueryOptions).
+	Plan string
+}
+
+// Failed returns true if the statement returned an error other than sql.ErrNoRows.
+func (e *QueryEvent) Failed() bool {
+	return e.Err != nil && !errors.Is(e.Err, sql.ErrNoRows)
+}
+
+// String formats the event as a single line, e.g.
+// "SELECT * FROM users WHERE id = $1 [1] [SELECT] [1 rows] 1.2ms". Statement is
+// omitted if it is builder.KindRaw.
+func (e *QueryEvent) String() string {
+	var sb strings.Builder
+	sb.WriteString(e.SQL)
+	if len(e.Params) > 0 {
+		fmt.Fprintf(&sb, " %v", e.Params)
+	}
+	if e.Statement != builder.KindRaw {
+		fmt.Fprintf(&sb, " [%v]", e.Statement)
+	}
+	if e.Rows >= 0 {
+		fmt.Fprintf(&sb, " [%d rows]", e.Rows)
+	}
+	fmt.Fprintf(&sb, " %v", e.Duration)
+	if e.Err != nil {
+		fmt.Fprintf(&sb, ": %v", e.Err)
+	}
+	return sb.String()
+}
+
 // defaultLogger is a logging adapter which uses syreclabs.com/go/loggie
-// for logging. Use SetLogger() to change.
+// for logging. Use WithLogger or SetLogger to change.
 type defaultLogger struct {
 	logger loggie.Logger
 }
@@ -17,7 +96,14 @@ type defaultLogger struct {
 const defaultLoggerName = "sql"
 
 func newDefaultLogger() Logger {
-	return &defaultLogger{loggie.New(defaultLoggerName)}
+	return LoggieLogger(loggie.New(defaultLoggerName))
+}
+
+// LoggieLogger returns Logger which writes to a loggie.Logger. Messages and query events
+// are logged at debug level, slow queries at warning level and failed queries at error
+// level, which loggie follows with the stack trace.
+func LoggieLogger(l loggie.Logger) Logger {
+	return &defaultLogger{l}
 }
 
 func (l *defaultLogger) Printf(format string, v ...interface{}) {
@@ -27,3 +113,19 @@ func (l *defaultLogger) Printf(format string, v ...interface{}) {
 func (l *defaultLogger) SetLevel(lvl int) {
 	l.logger.SetLevel(lvl)
 }
+
+func (l *defaultLogger) LogQuery(ctx context.Context, e *QueryEvent) {
+	if e.Failed() {
+		l.logger.Errorf("%s", e)
+		return
+	}
+	if e.Slow {
+		if e.Plan != "" {
+			l.logger.Warningf("SLOW %s\n%s", e, e.Plan)
+			return
+		}
+		l.logger.Warningf("SLOW %s", e)
+		return
+	}
+	l.logger.Debugf("%s", e)
+}
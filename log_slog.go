@@ -0,0 +1,57 @@
+//go:build go1.21
+// +build go1.21
+
+package prequel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// SlogLogger returns Logger which writes to a log/slog Logger. Messages and query events
+// are logged at debug level, slow queries at warning level and failed queries at error
+// level. Query events are logged with kind, sql, params, duration, rows and error
+// attributes, slow queries also with slow, start and plan attributes. SetLevel has no
+// effect, as levels are controlled by the slog handler.
+func SlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l *slogLogger) Printf(format string, v ...interface{}) {
+	l.logger.Debug(fmt.Sprintf(format, v...))
+}
+
+func (l *slogLogger) SetLevel(lvl int) {}
+
+func (l *slogLogger) LogQuery(ctx context.Context, e *QueryEvent) {
+	lvl := slog.LevelDebug
+	if e.Failed() {
+		lvl = slog.LevelError
+	} else if e.Slow {
+		lvl = slog.LevelWarn
+	}
+	attrs := []slog.Attr{
+		slog.String("kind", string(e.Kind)),
+		slog.String("statement", e.Statement.String()),
+		slog.String("sql", e.SQL),
+		slog.Any("params", e.Params),
+		slog.Duration("duration", e.Duration),
+		slog.Int64("rows", e.Rows),
+	}
+	if e.Slow {
+		attrs = append(attrs, slog.Bool("slow", true), slog.Time("start", e.Start))
+		if e.Plan != "" {
+			attrs = append(attrs, slog.Any("plan", json.RawMessage(e.Plan)))
+		}
+	}
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("error", e.Err))
+	}
+	l.logger.LogAttrs(ctx, lvl, "query", attrs...)
+}
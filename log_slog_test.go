@@ -0,0 +1,43 @@
+//go:build go1.21
+// +build go1.21
+
+package prequel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	l := SlogLogger(slog.New(h)).(QueryLogger)
+
+	l.LogQuery(context.Background(), &QueryEvent{Kind: KindGet, Statement: builder.KindSelect, SQL: "SELECT $1", Params: []interface{}{1}, Duration: time.Millisecond, Rows: 1})
+	l.LogQuery(context.Background(), &QueryEvent{Kind: KindExec, SQL: "DELETE FROM users", Rows: -1, Err: errors.New("failed")})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected %d lines, got %q", 2, lines)
+	}
+	var records [2]map[string]interface{}
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r := records[0]; r["level"] != "DEBUG" || r["kind"] != "get" || r["statement"] != "SELECT" || r["sql"] != "SELECT $1" || r["rows"] != 1.0 || r["error"] != nil {
+		t.Errorf("unexpected record %v", r)
+	}
+	if r := records[1]; r["level"] != "ERROR" || r["kind"] != "exec" || r["statement"] != "RAW" || r["error"] != "failed" {
+		t.Errorf("unexpected record %v", r)
+	}
+}
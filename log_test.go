@@ -0,0 +1,109 @@
+package prequel
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"syreclabs.com/go/loggie"
+	"syreclabs.com/go/prequel/builder"
+)
+
+type eventLogger struct {
+	captureLogger
+	events []*QueryEvent
+}
+
+func (l *eventLogger) LogQuery(ctx context.Context, e *QueryEvent) {
+	l.events = append(l.events, e)
+}
+
+type fakeExecer struct {
+	res sql.Result
+	err error
+}
+
+func (e fakeExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.res, e.err
+}
+
+func TestQueryEvent(t *testing.T) {
+	e := &QueryEvent{Kind: KindGet, Statement: builder.KindSelect, SQL: "SELECT * FROM users WHERE id = $1", Params: []interface{}{1}, Duration: time.Millisecond, Rows: 1}
+	if s := e.String(); s != "SELECT * FROM users WHERE id = $1 [1] [SELECT] [1 rows] 1ms" {
+		t.Errorf("unexpected string %q", s)
+	}
+	if e.Failed() {
+		t.Error("expected event not to be failed")
+	}
+
+	e = &QueryEvent{Kind: KindGet, SQL: "SELECT 1", Duration: time.Millisecond, Rows: 0, Err: sql.ErrNoRows}
+	if e.Failed() {
+		t.Error("expected sql.ErrNoRows not to fail the event")
+	}
+
+	e = &QueryEvent{Kind: KindExec, SQL: "DELETE FROM users", Duration: time.Millisecond, Rows: -1, Err: errors.New("failed")}
+	if s := e.String(); s != "DELETE FROM users 1ms: failed" {
+		t.Errorf("unexpected string %q", s)
+	}
+	if !e.Failed() {
+		t.Error("expected event to be failed")
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+	l := &eventLogger{}
+	cfg := newConfig([]Option{WithLogger(l)})
+
+	ctx := context.Background()
+	del := builder.Delete("users").Where("id = $1", 1)
+	if _, err := doExec(ctx, cfg, fakeExecer{res: driver.RowsAffected(2)}, del); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("failed")
+	if _, err := doExecRaw(ctx, cfg, fakeExecer{err: failed}, "DELETE FROM users"); err != failed {
+		t.Fatalf("expected err to be %v, got %v", failed, err)
+	}
+
+	if len(l.events) != 2 {
+		t.Fatalf("expected %d events, got %d", 2, len(l.events))
+	}
+	e := l.events[0]
+	if e.Kind != KindExec || e.Statement != builder.KindDelete || e.SQL != "DELETE FROM users WHERE (id = $1)" || len(e.Params) != 1 || e.Rows != 2 || e.Err != nil {
+		t.Errorf("unexpected event %#v", e)
+	}
+	e = l.events[1]
+	if e.Kind != KindExec || e.Rows != -1 || e.Err != failed {
+		t.Errorf("unexpected event %#v", e)
+	}
+
+	// loggers which do not implement QueryLogger receive formatted events
+	cl := &captureLogger{}
+	doExecRaw(ctx, newConfig([]Option{WithLogger(cl)}), fakeExecer{err: failed}, "DELETE FROM users")
+	if len(cl.lines) != 1 || !strings.HasPrefix(cl.lines[0], "DELETE FROM users ") || !strings.HasSuffix(cl.lines[0], ": failed") {
+		t.Errorf("unexpected lines %q", cl.lines)
+	}
+}
+
+func TestLoggieLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := LoggieLogger(loggie.NewLogger(&buf, "sql", loggie.NewTextFormatter(loggie.Flevel))).(QueryLogger)
+
+	l.LogQuery(context.Background(), &QueryEvent{SQL: "SELECT 1", Rows: 1})
+	l.LogQuery(context.Background(), &QueryEvent{SQL: "SELECT 2", Rows: -1, Err: errors.New("failed")})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 2 { // error level is followed by the stack trace
+		t.Fatalf("expected at least %d lines, got %q", 2, lines)
+	}
+	if !strings.Contains(lines[0], "DBG") || !strings.Contains(lines[0], "SELECT 1 [1 rows]") {
+		t.Errorf("unexpected debug line %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "ERR") || !strings.Contains(lines[1], "SELECT 2") {
+		t.Errorf("unexpected error line %q", lines[1])
+	}
+}
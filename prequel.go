@@ -4,19 +4,24 @@ package prequel // import "syreclabs.com/go/prequel"
 import (
 	"context"
 	"database/sql"
-	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"syreclabs.com/go/prequel/builder"
 )
 
-// Queryer is an interface used by Select and Get
+// Queryer is an interface used by Select, Get, Query and Each.
 type Queryer interface {
 	Select(ctx context.Context, b builder.Builder, dest interface{}) error
 	SelectRaw(ctx context.Context, dest interface{}, q string, params ...interface{}) error
 	Get(ctx context.Context, b builder.Builder, dest interface{}) error
 	GetRaw(ctx context.Context, dest interface{}, q string, params ...interface{}) error
+	Query(ctx context.Context, b builder.Builder) (*Rows, error)
+	QueryRaw(ctx context.Context, q string, params ...interface{}) (*Rows, error)
+	Each(ctx context.Context, b builder.Builder, fn func(*Rows) error) error
 }
 
 // Execer is an interface used by Exec and MustExec.
@@ -41,40 +46,46 @@ type Beginner interface {
 	MustBeginTx(ctx context.Context, opts *sql.TxOptions) *Tx
 }
 
-var log = newDefaultLogger()
-
-// SetLogger allows changing logging adapter used by prequel.
-func SetLogger(logger Logger) {
-	log = logger
-}
-
-func SetLogLevel(lvl int) {
-	log.SetLevel(lvl)
+// Session is an interface implemented by DB, Tx and Conn, which allows running
+// queries and beginning transactions regardless of whether a transaction is
+// already in progress. Transactions begun with Tx are nested using savepoints.
+type Session interface {
+	Runner
+	Beginner
 }
 
 // DB is a wrapper around sqlx.DB which supports builder.Builder.
 type DB struct {
 	DB *sqlx.DB
+	// Dialect is used to build statements. It is picked from driverName when DB is
+	// created and inherited by transactions and connections.
+	Dialect builder.Dialect
+
+	cfg     *config
+	leaksMu sync.RWMutex
+	leaks   *leakTracker
+	stmtsMu sync.RWMutex
+	stmts   *stmtCache
 }
 
 // NewDB is a wrapper for sqlx.NewDb that returns *prequel.DB.
-func NewDB(db *sql.DB, driverName string) *DB {
-	return &DB{sqlx.NewDb(db, driverName)}
+func NewDB(db *sql.DB, driverName string, opts ...Option) *DB {
+	return newDB(sqlx.NewDb(db, driverName), driverName, opts)
 }
 
 // Open is a wrapper for sqlx.Open that returns *prequel.DB.
-func Open(driverName, dataSourceName string) (*DB, error) {
+func Open(driverName, dataSourceName string, opts ...Option) (*DB, error) {
 	sqlxdb, err := sqlx.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
-	return &DB{sqlxdb}, nil
+	return newDB(sqlxdb, driverName, opts), nil
 }
 
 // MustOpen is a wrapper for sqlx.MustOpen that returns *prequel.DB.
 // This method will panic on error.
-func MustOpen(driverName, dataSourceName string) *DB {
-	db, err := Open(driverName, dataSourceName)
+func MustOpen(driverName, dataSourceName string, opts ...Option) *DB {
+	db, err := Open(driverName, dataSourceName, opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -82,82 +93,95 @@ func MustOpen(driverName, dataSourceName string) *DB {
 }
 
 // Connect is a wrapper for sqlx.Connect that returns *prequel.DB.
-func Connect(ctx context.Context, driverName, dataSourceName string) (*DB, error) {
+func Connect(ctx context.Context, driverName, dataSourceName string, opts ...Option) (*DB, error) {
 	sqlxdb, err := sqlx.ConnectContext(ctx, driverName, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
-	return &DB{sqlxdb}, nil
+	return newDB(sqlxdb, driverName, opts), nil
 }
 
 // MustConnect is a wrapper for sqlx.MustConnect that returns *prequel.DB.
 // This method will panic on error.
-func MustConnect(ctx context.Context, driverName, dataSourceName string) *DB {
-	db, err := Connect(ctx, driverName, dataSourceName)
+func MustConnect(ctx context.Context, driverName, dataSourceName string, opts ...Option) *DB {
+	db, err := Connect(ctx, driverName, dataSourceName, opts...)
 	if err != nil {
 		panic(err)
 	}
 	return db
 }
 
+func newDB(sqlxdb *sqlx.DB, driverName string, opts []Option) *DB {
+	cfg := newConfig(opts)
+	for _, fn := range cfg.pool {
+		fn(sqlxdb.DB)
+	}
+	db := &DB{DB: sqlxdb, Dialect: builder.DialectFor(driverName), cfg: cfg}
+	cfg.db = db
+	return db
+}
+
 // Select using this DB.
 func (db *DB) Select(ctx context.Context, b builder.Builder, dest interface{}) error {
-	return doSelect(ctx, db.DB, b, dest)
+	return doSelect(ctx, db.cfg, db.runner(), builder.WithDialect(db.Dialect, b), dest)
 }
 
 func (db *DB) SelectRaw(ctx context.Context, dest interface{}, sql string, params ...interface{}) error {
-	return doSelectRaw(ctx, db.DB, dest, sql, params...)
+	return doSelectRaw(ctx, db.cfg, db.DB, dest, sql, params...)
 }
 
 // Get using this DB.
 func (db *DB) Get(ctx context.Context, b builder.Builder, dest interface{}) error {
-	return doGet(ctx, db.DB, b, dest)
+	return doGet(ctx, db.cfg, db.runner(), builder.WithDialect(db.Dialect, b), dest)
 }
 
 func (db *DB) GetRaw(ctx context.Context, dest interface{}, sql string, params ...interface{}) error {
-	return doGetRaw(ctx, db.DB, dest, sql, params...)
+	return doGetRaw(ctx, db.cfg, db.DB, dest, sql, params...)
 }
 
 // Exec using this DB.
 func (db *DB) Exec(ctx context.Context, b builder.Builder) (sql.Result, error) {
-	return doExec(ctx, db.DB, b)
+	return doExec(ctx, db.cfg, db.runner(), builder.WithDialect(db.Dialect, b))
 }
 
 func (db *DB) ExecRaw(ctx context.Context, sql string, params ...interface{}) (sql.Result, error) {
-	return doExecRaw(ctx, db.DB, sql, params...)
+	return doExecRaw(ctx, db.cfg, db.DB, sql, params...)
 }
 
 // MustExec using this DB. This method will panic on error.
 func (db *DB) MustExec(ctx context.Context, b builder.Builder) sql.Result {
-	return doMustExec(ctx, db.DB, b)
+	return doMustExec(ctx, db.cfg, db.runner(), builder.WithDialect(db.Dialect, b))
 }
 
 func (db *DB) MustExecRaw(ctx context.Context, sql string, params ...interface{}) sql.Result {
-	return doMustExecRaw(ctx, db.DB, sql, params...)
+	return doMustExecRaw(ctx, db.cfg, db.DB, sql, params...)
 }
 
 // Begin starts a new transaction using this DB.
 func (db *DB) Begin(ctx context.Context) (*Tx, error) {
-	defer logf(time.Now(), "BEGIN")
-	sqlxtx, err := db.DB.BeginTxx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	return &Tx{sqlxtx}, nil
+	defer db.cfg.logf(time.Now(), "BEGIN")
+	return db.beginTx(ctx, nil)
 }
 
 // BeginTx starts a new transaction using this DB.
 func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
 	if opts != nil {
-		defer logf(time.Now(), "BEGIN [opts: %#v]", opts)
+		defer db.cfg.logf(time.Now(), "BEGIN [opts: %#v]", opts)
 	} else {
-		defer logf(time.Now(), "BEGIN")
+		defer db.cfg.logf(time.Now(), "BEGIN")
 	}
-	sqlxtx, err := db.DB.BeginTxx(ctx, opts)
+	return db.beginTx(ctx, opts)
+}
+
+func (db *DB) beginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := beginTx(ctx, db.cfg, db.DB, opts)
 	if err != nil {
 		return nil, err
 	}
-	return &Tx{sqlxtx}, nil
+	tx.dialect = db.Dialect
+	tx.db = db
+	tx.leak = db.tracker().track("Tx", tx)
+	return tx, nil
 }
 
 // MustBegin starts a new transaction using this DB. This method will panic on error.
@@ -187,7 +211,9 @@ func (db *DB) Conn(ctx context.Context) (*Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Conn{sqlxconn}, nil
+	conn := &Conn{Conn: sqlxconn, dialect: db.Dialect, cfg: db.cfg, db: db, leaks: db.tracker()}
+	conn.leak = conn.leaks.track("Conn", conn)
+	return conn, nil
 }
 
 // Conn returns a single connection using this DB and panic on error.
@@ -203,126 +229,239 @@ func (db *DB) MustConn(ctx context.Context) *Conn {
 }
 
 // Tx is a wrapper around sqlx.Tx which supports builder.Builder.
+// Transactions started with Tx.Begin are nested using savepoints.
 type Tx struct {
-	Tx *sqlx.Tx
+	Tx      *sqlx.Tx
+	dialect builder.Dialect
+	cfg     *config
+	db      *DB                // DB the transaction belongs to
+	opts    *sql.TxOptions     // options of root Tx
+	cancel  context.CancelFunc // releases the transaction timeout of root Tx
+
+	// nested transaction state
+	parent     *Tx
+	ctx        context.Context
+	savepoint  string
+	savepoints int // number of savepoints created by nested transactions of root Tx
+	done       bool
+
+	// hooks and their counts at each savepoint created with Savepoint
+	onCommit   []func()
+	onRollback []func()
+	marks      []savepointMark
+
+	leak *tracked
 }
 
 // Select using this transaction.
 func (tx *Tx) Select(ctx context.Context, b builder.Builder, dest interface{}) error {
-	return doSelect(ctx, tx.Tx, b, dest)
+	return doSelect(ctx, tx.cfg, tx.Tx, builder.WithDialect(tx.dialect, b), dest)
 }
 
 func (tx *Tx) SelectRaw(ctx context.Context, dest interface{}, sql string, params ...interface{}) error {
-	return doSelectRaw(ctx, tx.Tx, dest, sql, params...)
+	return doSelectRaw(ctx, tx.cfg, tx.Tx, dest, sql, params...)
 }
 
 // Get using this transaction.
 func (tx *Tx) Get(ctx context.Context, b builder.Builder, dest interface{}) error {
-	return doGet(ctx, tx.Tx, b, dest)
+	return doGet(ctx, tx.cfg, tx.Tx, builder.WithDialect(tx.dialect, b), dest)
 }
 
 func (tx *Tx) GetRaw(ctx context.Context, dest interface{}, sql string, params ...interface{}) error {
-	return doGetRaw(ctx, tx.Tx, dest, sql, params...)
+	return doGetRaw(ctx, tx.cfg, tx.Tx, dest, sql, params...)
 }
 
 // Exec using this transaction.
 func (tx *Tx) Exec(ctx context.Context, b builder.Builder) (sql.Result, error) {
-	return doExec(ctx, tx.Tx, b)
+	return doExec(ctx, tx.cfg, tx.Tx, builder.WithDialect(tx.dialect, b))
 }
 
 func (tx *Tx) ExecRaw(ctx context.Context, sql string, params ...interface{}) (sql.Result, error) {
-	return doExecRaw(ctx, tx.Tx, sql, params...)
+	return doExecRaw(ctx, tx.cfg, tx.Tx, sql, params...)
 }
 
 // Must Exec using this transaction and panic on error.
 func (tx *Tx) MustExec(ctx context.Context, b builder.Builder) sql.Result {
-	return doMustExec(ctx, tx.Tx, b)
+	return doMustExec(ctx, tx.cfg, tx.Tx, builder.WithDialect(tx.dialect, b))
 }
 
 func (tx *Tx) MustExecRaw(ctx context.Context, sql string, params ...interface{}) sql.Result {
-	return doMustExecRaw(ctx, tx.Tx, sql, params...)
+	return doMustExecRaw(ctx, tx.cfg, tx.Tx, sql, params...)
 }
 
-// Commit this transaction.
+// Commit this transaction and run OnCommit hooks. Nested transaction releases its
+// savepoint and passes its hooks to the parent transaction.
 func (tx *Tx) Commit() error {
-	defer logf(time.Now(), "COMMIT")
-	return tx.Tx.Commit()
+	if tx.parent != nil {
+		if tx.done {
+			return sql.ErrTxDone
+		}
+		tx.done = true
+		if err := tx.parent.Release(tx.ctx, tx.savepoint); err != nil {
+			return err
+		}
+		tx.parent.onCommit = append(tx.parent.onCommit, tx.onCommit...)
+		tx.parent.onRollback = append(tx.parent.onRollback, tx.onRollback...)
+		return nil
+	}
+	if err := tx.end(KindCommit, tx.Tx.Commit); err != nil {
+		return err
+	}
+	runHooks(tx.onCommit)
+	return nil
 }
 
-// Rollback this transaction.
+// Rollback this transaction and run OnRollback hooks. Nested transaction rolls back
+// to its savepoint, releases it and discards its hooks.
 func (tx *Tx) Rollback() error {
-	defer logf(time.Now(), "ROLLBACK")
-	return tx.Tx.Rollback()
+	if tx.parent != nil {
+		if tx.done {
+			return sql.ErrTxDone
+		}
+		tx.done = true
+		if err := tx.parent.RollbackTo(tx.ctx, tx.savepoint); err != nil {
+			return err
+		}
+		return tx.parent.Release(tx.ctx, tx.savepoint)
+	}
+	if err := tx.end(KindRollback, tx.Tx.Rollback); err != nil {
+		return err
+	}
+	runHooks(tx.onRollback)
+	return nil
+}
+
+// end commits or rolls back root transaction using fn, wrapped in interceptors.
+func (tx *Tx) end(kind QueryKind, fn func() error) error {
+	query := strings.ToUpper(string(kind))
+	ran := false
+	err := tx.cfg.intercept(tx.ctx, &Call{Kind: kind, SQL: query, Rows: -1}, func(ctx context.Context, call *Call) error {
+		ran = true
+		start := time.Now()
+		err := fn()
+		tx.cfg.logf(start, "%s", query)
+		return err
+	})
+	if !ran {
+		if err == nil {
+			err = errShortCircuited(kind)
+		}
+		return err // transaction is still active
+	}
+	tx.leak.done()
+	if tx.cancel != nil { // not set for Tx created by wrapping sqlx.Tx
+		tx.cancel()
+	}
+	return err
 }
 
 // Conn is a wrapper around sqlx.Conn which supports builder.Builder.
 type Conn struct {
-	Conn *sqlx.Conn
+	Conn    *sqlx.Conn
+	dialect builder.Dialect
+	cfg     *config
+	db      *DB // DB the connection belongs to
+	leaks   *leakTracker
+	leak    *tracked
 }
 
 // Close returns this connection to the connection pool.
 func (conn *Conn) Close() error {
+	conn.leak.done()
 	return conn.Conn.Close()
 }
 
 // Select using this connection.
 func (conn *Conn) Select(ctx context.Context, b builder.Builder, dest interface{}) error {
-	return doSelect(ctx, conn.Conn, b, dest)
+	return doSelect(ctx, conn.cfg, conn.Conn, builder.WithDialect(conn.dialect, b), dest)
 }
 
 func (conn *Conn) SelectRaw(ctx context.Context, dest interface{}, sql string, params ...interface{}) error {
-	return doSelectRaw(ctx, conn.Conn, dest, sql, params...)
+	return doSelectRaw(ctx, conn.cfg, conn.Conn, dest, sql, params...)
 }
 
 // Get using this connection.
 func (conn *Conn) Get(ctx context.Context, b builder.Builder, dest interface{}) error {
-	return doGet(ctx, conn.Conn, b, dest)
+	return doGet(ctx, conn.cfg, conn.Conn, builder.WithDialect(conn.dialect, b), dest)
 }
 
 func (conn *Conn) GetRaw(ctx context.Context, dest interface{}, sql string, params ...interface{}) error {
-	return doGetRaw(ctx, conn.Conn, dest, sql, params...)
+	return doGetRaw(ctx, conn.cfg, conn.Conn, dest, sql, params...)
 }
 
 // Exec using this connection.
 func (conn *Conn) Exec(ctx context.Context, b builder.Builder) (sql.Result, error) {
-	return doExec(ctx, conn.Conn, b)
+	return doExec(ctx, conn.cfg, conn.Conn, builder.WithDialect(conn.dialect, b))
 }
 
 func (conn *Conn) ExecRaw(ctx context.Context, sql string, params ...interface{}) (sql.Result, error) {
-	return doExecRaw(ctx, conn.Conn, sql, params...)
+	return doExecRaw(ctx, conn.cfg, conn.Conn, sql, params...)
 }
 
 // MustExec using this connection. This method will panic on error.
 func (conn *Conn) MustExec(ctx context.Context, b builder.Builder) sql.Result {
-	return doMustExec(ctx, conn.Conn, b)
+	return doMustExec(ctx, conn.cfg, conn.Conn, builder.WithDialect(conn.dialect, b))
 }
 
 func (conn *Conn) MustExecRaw(ctx context.Context, sql string, params ...interface{}) sql.Result {
-	return doMustExecRaw(ctx, conn.Conn, sql, params...)
+	return doMustExecRaw(ctx, conn.cfg, conn.Conn, sql, params...)
 }
 
 // Begin starts a new transaction using this connection.
 func (conn *Conn) Begin(ctx context.Context) (*Tx, error) {
-	defer logf(time.Now(), "BEGIN")
-	sqlxtx, err := conn.Conn.BeginTxx(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	return &Tx{sqlxtx}, nil
+	defer conn.cfg.logf(time.Now(), "BEGIN")
+	return conn.beginTx(ctx, nil)
 }
 
 // BeginTx starts a new transaction using this connection.
 func (conn *Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
 	if opts != nil {
-		defer logf(time.Now(), "BEGIN [Isolation:%v ReadOnly:%v]", opts.Isolation, opts.ReadOnly)
+		defer conn.cfg.logf(time.Now(), "BEGIN [Isolation:%v ReadOnly:%v]", opts.Isolation, opts.ReadOnly)
 	} else {
-		defer logf(time.Now(), "BEGIN [nil opts]")
+		defer conn.cfg.logf(time.Now(), "BEGIN [nil opts]")
 	}
-	sqlxtx, err := conn.Conn.BeginTxx(ctx, opts)
+	return conn.beginTx(ctx, opts)
+}
+
+func (conn *Conn) beginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := beginTx(ctx, conn.cfg, conn.Conn, opts)
 	if err != nil {
 		return nil, err
 	}
-	return &Tx{sqlxtx}, nil
+	tx.dialect = conn.dialect
+	tx.db = conn.db
+	tx.leak = conn.leaks.track("Tx", tx)
+	return tx, nil
+}
+
+type txBeginner interface {
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+// beginTx begins a root transaction with the default timeout, wrapped in interceptors.
+func beginTx(ctx context.Context, cfg *config, b txBeginner, opts *sql.TxOptions) (*Tx, error) {
+	var tx *Tx
+	err := cfg.intercept(ctx, &Call{Kind: KindBegin, SQL: "BEGIN", Rows: -1, TxOptions: opts}, func(ctx context.Context, call *Call) error {
+		txctx, cancel := cfg.txContext(ctx)
+		sqlxtx, err := b.BeginTxx(txctx, call.TxOptions)
+		if err != nil {
+			cancel()
+			return err
+		}
+		tx = &Tx{Tx: sqlxtx, cfg: cfg, opts: call.TxOptions, ctx: ctx, cancel: cancel}
+		return nil
+	})
+	if err == nil && tx == nil {
+		err = errShortCircuited(KindBegin)
+	}
+	if err != nil {
+		if tx != nil { // failed by an interceptor after begin
+			tx.Tx.Rollback()
+			tx.cancel()
+		}
+		return nil, err
+	}
+	return tx, nil
 }
 
 // MustBegin starts a new transaction using this DB. This method will panic on error.
@@ -343,130 +482,123 @@ func (conn *Conn) MustBeginTx(ctx context.Context, opts *sql.TxOptions) *Tx {
 	return tx
 }
 
-// // Stmt is a wrapper around sqlx.Stmt which supports builder.Builder.
-// type Stmt struct {
-// 	Stmt *sqlx.Stmt
-// }
-
-// // Select using this Stmt.
-// func (stmt *Stmt) Select(ctx context.Context, b builder.Builder, dest interface{}) error {
-// 	return doSelect(ctx, stmtWrapper{stmt.Stmt}, b, dest)
-// }
-
-// // Get using this Stmt.
-// func (stmt *Stmt) Get(ctx context.Context, b builder.Builder, dest interface{}) error {
-// 	return doGet(ctx, stmtWrapper{stmt.Stmt}, b, dest)
-// }
-
-// // Exec using this Stmt.
-// func (stmt *Stmt) Exec(ctx context.Context, b builder.Builder) (sql.Result, error) {
-// 	return doExec(ctx, stmtWrapper{stmt.Stmt}, b)
-// }
-
-// // MustExec using this Stmt. This method will panic on error.
-// func (stmt *Stmt) MustExec(ctx context.Context, b builder.Builder) sql.Result {
-// 	return doMustExec(ctx, stmtWrapper{stmt.Stmt}, b)
-// }
-
-// // stmtWrapper is an unexported wrapper which implements Queryer and Execer by
-// // delegating to the underlying sqlx.Stmt.
-// type stmtWrapper struct{ *sqlx.Stmt }
-
-// func (w stmtWrapper) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-// 	return w.Stmt.QueryContext(ctx, args...)
-// }
-
-// func (w stmtWrapper) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
-// 	return w.Stmt.QueryxContext(ctx, args...)
-// }
-
-// func (w stmtWrapper) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
-// 	return w.Stmt.QueryRowxContext(ctx, args...)
-// }
-
-// func (w stmtWrapper) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-// 	return w.Stmt.ExecContext(ctx, args...)
-// }
-
 // doSelect builds the query using the provided builder, executes it with queryer and
 // scans each row into dest, which must be a slice. If the slice elements are scannable,
 // then the result set must have only one column. Otherwise, sqlx.StructScan is used.
-func doSelect(ctx context.Context, q sqlx.QueryerContext, b builder.Builder, dest interface{}) error {
+func doSelect(ctx context.Context, cfg *config, q sqlx.QueryerContext, b builder.Builder, dest interface{}) error {
 	start := time.Now()
-	sql, params, err := b.Build()
-	if err != nil {
+	call := &Call{Kind: KindSelect, Builder: b, Dest: dest}
+	if err := buildCall(ctx, cfg, call); err != nil {
 		return err
 	}
-	defer logSql(start, sql, params)
-	return sqlx.SelectContext(ctx, q, dest, sql, params...)
+	return selectContext(ctx, cfg, q, start, call)
 }
 
-func doSelectRaw(ctx context.Context, q sqlx.QueryerContext, dest interface{}, sql string, params ...interface{}) error {
-	start := time.Now()
-	defer logSql(start, sql, params)
-	return sqlx.SelectContext(ctx, q, dest, sql, params...)
+func doSelectRaw(ctx context.Context, cfg *config, q sqlx.QueryerContext, dest interface{}, sql string, params ...interface{}) error {
+	return selectContext(ctx, cfg, q, time.Now(), &Call{Kind: KindSelect, SQL: sql, Params: params, Dest: dest})
+}
+
+func selectContext(ctx context.Context, cfg *config, q sqlx.QueryerContext, start time.Time, call *Call) error {
+	call.Rows = -1
+	return cfg.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+		qctx, cancel := cfg.queryContext(ctx)
+		defer cancel()
+		err := sqlx.SelectContext(qctx, q, call.Dest, call.SQL, call.Params...)
+		if err == nil {
+			if v := reflect.Indirect(reflect.ValueOf(call.Dest)); v.Kind() == reflect.Slice {
+				call.Rows = int64(v.Len())
+			}
+		}
+		cfg.logStatement(ctx, explainer(q), start, KindSelect, call.SQL, call.Params, call.Rows, err)
+		return err
+	})
 }
 
 // doGet builds the query using the provided builder, executes it with queryer and scans the
 // resulting row to dest. If dest is scannable, the result must only have one column. Otherwise,
 // sqlx.StructScan is used. Get will return sql.ErrNoRows if the result set is empty.
-func doGet(ctx context.Context, q sqlx.QueryerContext, b builder.Builder, dest interface{}) error {
+func doGet(ctx context.Context, cfg *config, q sqlx.QueryerContext, b builder.Builder, dest interface{}) error {
 	start := time.Now()
-	sql, params, err := b.Build()
-	if err != nil {
+	call := &Call{Kind: KindGet, Builder: b, Dest: dest}
+	if err := buildCall(ctx, cfg, call); err != nil {
 		return err
 	}
-	defer logSql(start, sql, params)
-	return sqlx.GetContext(ctx, q, dest, sql, params...)
-}
-
-func doGetRaw(ctx context.Context, q sqlx.QueryerContext, dest interface{}, sql string, params ...interface{}) error {
-	start := time.Now()
-	defer logSql(start, sql, params)
-	return sqlx.GetContext(ctx, q, dest, sql, params...)
+	return getContext(ctx, cfg, q, start, call)
+}
+
+func doGetRaw(ctx context.Context, cfg *config, q sqlx.QueryerContext, dest interface{}, sql string, params ...interface{}) error {
+	return getContext(ctx, cfg, q, time.Now(), &Call{Kind: KindGet, SQL: sql, Params: params, Dest: dest})
+}
+
+func getContext(ctx context.Context, cfg *config, q sqlx.QueryerContext, start time.Time, call *Call) error {
+	call.Rows = -1
+	return cfg.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+		qctx, cancel := cfg.queryContext(ctx)
+		defer cancel()
+		err := sqlx.GetContext(qctx, q, call.Dest, call.SQL, call.Params...)
+		switch err {
+		case nil:
+			call.Rows = 1
+		case sql.ErrNoRows:
+			call.Rows = 0
+		}
+		cfg.logStatement(ctx, explainer(q), start, KindGet, call.SQL, call.Params, call.Rows, err)
+		return err
+	})
 }
 
 // doExec builds the query using the provided builder and executes it with execer.
-func doExec(ctx context.Context, e sqlx.ExecerContext, b builder.Builder) (sql.Result, error) {
+func doExec(ctx context.Context, cfg *config, e sqlx.ExecerContext, b builder.Builder) (sql.Result, error) {
 	start := time.Now()
-	sql, params, err := b.Build()
+	call := &Call{Kind: KindExec, Builder: b}
+	if err := buildCall(ctx, cfg, call); err != nil {
+		return nil, err
+	}
+	return execContext(ctx, cfg, e, start, call)
+}
+
+func doExecRaw(ctx context.Context, cfg *config, e sqlx.ExecerContext, sql string, params ...interface{}) (sql.Result, error) {
+	return execContext(ctx, cfg, e, time.Now(), &Call{Kind: KindExec, SQL: sql, Params: params})
+}
+
+func execContext(ctx context.Context, cfg *config, e sqlx.ExecerContext, start time.Time, call *Call) (sql.Result, error) {
+	call.Rows = -1
+	err := cfg.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+		qctx, cancel := cfg.queryContext(ctx)
+		defer cancel()
+		res, err := e.ExecContext(qctx, call.SQL, call.Params...)
+		if err == nil {
+			call.Result = res
+			if n, err := res.RowsAffected(); err == nil {
+				call.Rows = n
+			}
+		}
+		cfg.logStatement(ctx, explainer(e), start, KindExec, call.SQL, call.Params, call.Rows, err)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer logSql(start, sql, params)
-	return e.ExecContext(ctx, sql, params...)
-}
-
-func doExecRaw(ctx context.Context, e sqlx.ExecerContext, sql string, params ...interface{}) (sql.Result, error) {
-	start := time.Now()
-	defer logSql(start, sql, params)
-	return e.ExecContext(ctx, sql, params...)
+	if call.Result == nil {
+		return nil, errShortCircuited(KindExec)
+	}
+	return call.Result, nil
 }
 
 // doMustExec builds the query using the provided builder and executes it with execer.
 // It will panic if there was an error.
-func doMustExec(ctx context.Context, e sqlx.ExecerContext, b builder.Builder) sql.Result {
-	res, err := doExec(ctx, e, b)
+func doMustExec(ctx context.Context, cfg *config, e sqlx.ExecerContext, b builder.Builder) sql.Result {
+	res, err := doExec(ctx, cfg, e, b)
 	if err != nil {
 		panic(err)
 	}
 	return res
 }
 
-func doMustExecRaw(ctx context.Context, e sqlx.ExecerContext, sql string, params ...interface{}) sql.Result {
-	res, err := doExecRaw(ctx, e, sql, params...)
+func doMustExecRaw(ctx context.Context, cfg *config, e sqlx.ExecerContext, sql string, params ...interface{}) sql.Result {
+	res, err := doExecRaw(ctx, cfg, e, sql, params...)
 	if err != nil {
 		panic(err)
 	}
 	return res
 }
-
-func logf(start time.Time, format string, args ...interface{}) {
-	elapsed := time.Since(start)
-	log.Printf("%s %v", fmt.Sprintf(format, args...), elapsed)
-}
-
-func logSql(start time.Time, sql string, params []interface{}) {
-	elapsed := time.Since(start)
-	log.Printf("%s %v %v", sql, params, elapsed)
-}
@@ -100,7 +100,9 @@ var _ Runner = (*DB)(nil)
 var _ Runner = (*Conn)(nil)
 var _ Runner = (*Tx)(nil)
 
-// var _ Runner = (*Stmt)(nil)
+var _ Session = (*DB)(nil)
+var _ Session = (*Conn)(nil)
+var _ Session = (*Tx)(nil)
 
 func TestSelectAll(t *testing.T) {
 	withSchema(context.Background(), func(ctx context.Context) {
@@ -937,3 +939,126 @@ func TestConnExecDelete(t *testing.T) {
 		}
 	})
 }
+
+func TestInsertChunked(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		t.Run("Exec", func(t *testing.T) {
+			b := builder.
+				Insert("users").
+				Columns("first_name", "last_name", "email")
+			for i := 0; i < 10; i++ {
+				b.Values("Chunk", "Exec", fmt.Sprintf("exec%d@example.com", i))
+			}
+
+			rows, err := db.InsertChunked(ctx, b, 3, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows != 10 {
+				t.Fatalf("expected RowsAffected to be %d, got %d", 10, rows)
+			}
+		})
+
+		t.Run("Returning", func(t *testing.T) {
+			b := builder.
+				Insert("users").
+				Columns("first_name", "last_name", "email").
+				Returning("id", "email")
+			for i := 0; i < 5; i++ {
+				b.Values("Chunk", "Returning", fmt.Sprintf("returning%d@example.com", i))
+			}
+
+			var users []*User
+			rows, err := db.InsertChunked(ctx, b, 2, &users)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows != 5 || len(users) != 5 {
+				t.Fatalf("expected %d records, got %d (%d rows affected)", 5, len(users), rows)
+			}
+		})
+
+		t.Run("Rollback", func(t *testing.T) {
+			b := builder.
+				Insert("users").
+				Columns("first_name", "last_name", "email").
+				Values("Chunk", "Rollback", "rollback@example.com").
+				Values("Chunk", "Rollback", "user@example.com")
+
+			if _, err := db.InsertChunked(ctx, b, 1, nil); err == nil {
+				t.Fatal("expected err not to be nil")
+			}
+
+			var count int
+			if err := db.Get(ctx, builder.Select("count(*)").From("users").Where("email = $1", "rollback@example.com"), &count); err != nil {
+				t.Fatal(err)
+			}
+			if count != 0 {
+				t.Fatalf("expected %d records, got %d", 0, count)
+			}
+		})
+	})
+}
+
+func TestExecUnnest(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		t.Run("Insert", func(t *testing.T) {
+			b := builder.
+				Insert("users").
+				Columns("first_name", "last_name", "email").
+				Values("Jane", "Doe", "janie@notmail.me").
+				Values("John", "Roe", "john@notmail.me").
+				Unnest()
+
+			res, err := db.Exec(ctx, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := res.RowsAffected()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows != 2 {
+				t.Fatalf("expected RowsAffected to be %d, got %d", 2, rows)
+			}
+		})
+
+		t.Run("Upsert", func(t *testing.T) {
+			b := builder.
+				Upsert("users", "(email)").
+				Columns("first_name", "last_name", "email").
+				Values("Janie", "Doe", "janie@notmail.me").
+				Values("Max", "Rockatansky", "max@notmail.me").
+				Unnest("varchar(30)", "text", "text")
+
+			res, err := db.Exec(ctx, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows, err := res.RowsAffected()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows != 2 {
+				t.Fatalf("expected RowsAffected to be %d, got %d", 2, rows)
+			}
+		})
+	})
+}
+
+func TestDialect(t *testing.T) {
+	examples := map[string]builder.Dialect{
+		"postgres": builder.Postgres,
+		"sqlite3":  builder.SQLite,
+		"mysql":    builder.MySQL,
+	}
+	for driver, expected := range examples {
+		if d := NewDB(nil, driver).Dialect; d != expected {
+			t.Errorf("%s: expected dialect %v, got %v", driver, expected, d)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"syreclabs.com/go/prequel/builder"
+)
+
+// Rows is a cursor over the result set of Query. Rows must be closed after use,
+// which happens automatically once Next returns false. The statement is logged
+// together with the number of rows read when Rows is closed.
+type Rows struct {
+	rows   *sqlx.Rows
+	ctx    context.Context
+	cfg    *config
+	cancel context.CancelFunc
+	sql    string
+	params []interface{}
+	start  time.Time
+	count  int64
+	closed bool
+}
+
+// Next prepares the next row for reading with Scan or StructScan. It returns
+// false and closes Rows when there are no more rows or an error occurred.
+func (r *Rows) Next() bool {
+	if r.closed {
+		return false
+	}
+	if r.rows.Next() {
+		r.count++
+		return true
+	}
+	r.Close()
+	return false
+}
+
+// Scan copies columns of the current row into dest.
+func (r *Rows) Scan(dest ...interface{}) error {
+	return r.rows.Scan(dest...)
+}
+
+// StructScan scans the current row into dest, which must be a pointer to a struct.
+func (r *Rows) StructScan(dest interface{}) error {
+	return r.rows.StructScan(dest)
+}
+
+// Columns returns the column names.
+func (r *Rows) Columns() ([]string, error) {
+	return r.rows.Columns()
+}
+
+// ColumnTypes returns column information.
+func (r *Rows) ColumnTypes() ([]*sql.ColumnType, error) {
+	return r.rows.ColumnTypes()
+}
+
+// Err returns the error, if any, encountered during iteration.
+func (r *Rows) Err() error {
+	return r.rows.Err()
+}
+
+// Count returns the number of rows read so far.
+func (r *Rows) Count() int64 {
+	return r.count
+}
+
+// Close closes Rows. It is safe to call Close more than once.
+func (r *Rows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	err := r.rows.Close()
+	logErr := r.rows.Err()
+	if logErr == nil {
+		logErr = err
+	}
+	r.cancel()
+	r.cfg.logQuery(r.ctx, r.start, KindQuery, r.sql, r.params, r.count, logErr)
+	return err
+}
+
+// Query using this DB.
+func (db *DB) Query(ctx context.Context, b builder.Builder) (*Rows, error) {
+	return doQuery(ctx, db.cfg, db.runner(), builder.WithDialect(db.Dialect, b))
+}
+
+func (db *DB) QueryRaw(ctx context.Context, sql string, params ...interface{}) (*Rows, error) {
+	return doQueryRaw(ctx, db.cfg, db.DB, sql, params...)
+}
+
+// Each calls fn for each row of the query using this DB.
+func (db *DB) Each(ctx context.Context, b builder.Builder, fn func(*Rows) error) error {
+	return doEach(ctx, db.cfg, db.runner(), builder.WithDialect(db.Dialect, b), fn)
+}
+
+// Query using this transaction.
+func (tx *Tx) Query(ctx context.Context, b builder.Builder) (*Rows, error) {
+	return doQuery(ctx, tx.cfg, tx.Tx, builder.WithDialect(tx.dialect, b))
+}
+
+func (tx *Tx) QueryRaw(ctx context.Context, sql string, params ...interface{}) (*Rows, error) {
+	return doQueryRaw(ctx, tx.cfg, tx.Tx, sql, params...)
+}
+
+// Each calls fn for each row of the query using this transaction.
+func (tx *Tx) Each(ctx context.Context, b builder.Builder, fn func(*Rows) error) error {
+	return doEach(ctx, tx.cfg, tx.Tx, builder.WithDialect(tx.dialect, b), fn)
+}
+
+// Query using this connection.
+func (conn *Conn) Query(ctx context.Context, b builder.Builder) (*Rows, error) {
+	return doQuery(ctx, conn.cfg, conn.Conn, builder.WithDialect(conn.dialect, b))
+}
+
+func (conn *Conn) QueryRaw(ctx context.Context, sql string, params ...interface{}) (*Rows, error) {
+	return doQueryRaw(ctx, conn.cfg, conn.Conn, sql, params...)
+}
+
+// Each calls fn for each row of the query using this connection.
+func (conn *Conn) Each(ctx context.Context, b builder.Builder, fn func(*Rows) error) error {
+	return doEach(ctx, conn.cfg, conn.Conn, builder.WithDialect(conn.dialect, b), fn)
+}
+
+// doQuery builds the query using the provided builder, executes it with queryer and
+// returns a cursor over the result set.
+func doQuery(ctx context.Context, cfg *config, q sqlx.QueryerContext, b builder.Builder) (*Rows, error) {
+	start := time.Now()
+	call := &Call{Kind: KindQuery, Builder: b}
+	if err := buildCall(ctx, cfg, call); err != nil {
+		return nil, err
+	}
+	return queryRows(ctx, cfg, q, start, call)
+}
+
+func doQueryRaw(ctx context.Context, cfg *config, q sqlx.QueryerContext, sql string, params ...interface{}) (*Rows, error) {
+	return queryRows(ctx, cfg, q, time.Now(), &Call{Kind: KindQuery, SQL: sql, Params: params})
+}
+
+// queryRows runs the query wrapped in interceptors. The default statement timeout applies
+// until Rows is closed.
+func queryRows(ctx context.Context, cfg *config, q sqlx.QueryerContext, start time.Time, call *Call) (*Rows, error) {
+	call.Rows = -1
+	var res *Rows
+	err := cfg.intercept(ctx, call, func(ctx context.Context, call *Call) error {
+		qctx, cancel := cfg.queryContext(ctx)
+		rows, err := q.QueryxContext(qctx, call.SQL, call.Params...)
+		if err != nil {
+			cancel()
+			cfg.logQuery(ctx, start, KindQuery, call.SQL, call.Params, -1, err)
+			return err
+		}
+		res = &Rows{rows: rows, ctx: ctx, cfg: cfg, cancel: cancel, sql: call.SQL, params: call.Params, start: start}
+		return nil
+	})
+	if err != nil {
+		if res != nil {
+			res.Close() // an interceptor failed the call after next returned
+		}
+		return nil, err
+	}
+	if res == nil {
+		return nil, errShortCircuited(KindQuery)
+	}
+	return res, nil
+}
+
+// doEach runs the query built by b with queryer and calls fn for each row. Iteration
+// stops at the first error returned by fn.
+func doEach(ctx context.Context, cfg *config, q sqlx.QueryerContext, b builder.Builder, fn func(*Rows) error) error {
+	rows, err := doQuery(ctx, cfg, q, b)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,100 @@
+package prequel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+func TestQuery(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		b := builder.
+			Select("id", "first_name", "last_name", "email").
+			From("users").
+			OrderBy("id")
+
+		t.Run("StructScan", func(t *testing.T) {
+			rows, err := db.Query(ctx, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var users []User
+			for rows.Next() {
+				var u User
+				if err := rows.StructScan(&u); err != nil {
+					t.Fatal(err)
+				}
+				users = append(users, u)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 3 || rows.Count() != 3 {
+				t.Fatalf("expected %d records, got %d", 3, len(users))
+			}
+			if users[0].Email != "user@example.com" {
+				t.Errorf("expected Email %q, got %q", "user@example.com", users[0].Email)
+			}
+		})
+
+		t.Run("Scan", func(t *testing.T) {
+			conn := db.MustConn(ctx)
+			defer conn.Close()
+
+			rows, err := conn.QueryRaw(ctx, "SELECT email FROM users WHERE id = $1", 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var email string
+			for rows.Next() {
+				if err := rows.Scan(&email); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if email != "john@mail.net" {
+				t.Errorf("expected Email %q, got %q", "john@mail.net", email)
+			}
+		})
+
+		t.Run("Each", func(t *testing.T) {
+			var emails []string
+			err := db.Each(ctx, b, func(rows *Rows) error {
+				var u User
+				if err := rows.StructScan(&u); err != nil {
+					return err
+				}
+				emails = append(emails, u.Email)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(emails) != 3 {
+				t.Fatalf("expected %d records, got %d", 3, len(emails))
+			}
+		})
+
+		t.Run("EachStop", func(t *testing.T) {
+			errStop := errors.New("stop")
+			var count int
+			err := db.Each(ctx, b, func(rows *Rows) error {
+				count++
+				return errStop
+			})
+			if err != errStop {
+				t.Fatalf("expected err to be %v, got %v", errStop, err)
+			}
+			if count != 1 {
+				t.Fatalf("expected %d calls, got %d", 1, count)
+			}
+		})
+	})
+}
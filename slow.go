@@ -0,0 +1,158 @@
+package prequel
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"syreclabs.com/go/prequel/builder"
+)
+
+// SlowQueryOptions configures handling of statements which exceed the threshold set
+// with WithSlowQueryThreshold.
+type SlowQueryOptions struct {
+	// Explain reruns slow Select, Get and Exec statements with EXPLAIN (FORMAT JSON) and
+	// the same parameters, and attaches the plan to the event. It is supported for
+	// PostgreSQL only. In transactions, EXPLAIN runs in a savepoint which is rolled back,
+	// so that its failure does not abort the transaction.
+	Explain bool
+	// Analyze uses EXPLAIN (ANALYZE, FORMAT JSON) for read-only SELECT statements (see
+	// builder.Info.ReadOnly), which runs them again to collect actual timings. Statements
+	// which modify data or lock rows are never analyzed. SELECT statements calling
+	// functions with side effects, such as nextval, are run twice, which the savepoint
+	// of a transaction undoes only for transactional changes.
+	Analyze bool
+	// SampleRate is the fraction of slow statements which are reported, e.g. 0.1 for 10%.
+	// Zero reports all of them.
+	SampleRate float64
+	// Limit is the maximum number of slow statements reported per Interval. Zero means
+	// no limit.
+	Limit int
+	// Interval is the rate limit interval, one second by default.
+	Interval time.Duration
+}
+
+// WithSlowQueryOptions configures slow statement reporting. Slow statements which are
+// sampled out or exceed the rate limit are logged as regular ones.
+func WithSlowQueryOptions(opts SlowQueryOptions) Option {
+	return func(c *config) {
+		c.slow.opts = opts
+	}
+}
+
+type slowLog struct {
+	opts SlowQueryOptions
+
+	mu     sync.Mutex
+	window time.Time
+	count  int
+}
+
+// allow returns true if the slow statement should be reported.
+func (s *slowLog) allow(now time.Time) bool {
+	if s.opts.SampleRate > 0 && rand.Float64() >= s.opts.SampleRate {
+		return false
+	}
+	if s.opts.Limit <= 0 {
+		return true
+	}
+	interval := s.opts.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.window) >= interval {
+		s.window = now
+		s.count = 0
+	}
+	if s.count >= s.opts.Limit {
+		return false
+	}
+	s.count++
+	return true
+}
+
+const explainSavepoint = "prequel_explain"
+
+// explain returns the JSON plan of the statement run with q. In transactions, the plan is
+// captured in a savepoint, which is rolled back to keep the transaction usable if EXPLAIN
+// fails and to undo the changes made by the analyzed statement.
+func (c *config) explain(ctx context.Context, q sqlx.QueryerContext, kind QueryKind, sql string, params []interface{}) (plan string, err error) {
+	query := "EXPLAIN (FORMAT JSON) " + sql
+	if c.slow.opts.Analyze && (kind == KindSelect || kind == KindGet) && isAnalyzable(sql) {
+		query = "EXPLAIN (ANALYZE, FORMAT JSON) " + sql
+	}
+
+	if tx, ok := q.(*sqlx.Tx); ok {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+explainSavepoint); err != nil {
+			return "", err
+		}
+		defer func() {
+			// the statement context may be canceled, use ctx to restore the transaction
+			if _, rerr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+explainSavepoint); rerr != nil && err == nil {
+				err = rerr
+			}
+			if _, rerr := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+explainSavepoint); rerr != nil && err == nil {
+				err = rerr
+			}
+		}()
+	}
+
+	qctx, cancel := c.queryContext(ctx)
+	defer cancel()
+	err = sqlx.GetContext(qctx, q, &plan, query, params...)
+	return plan, err
+}
+
+// isAnalyzable returns true if sql is a read-only statement, which does not modify data
+// or lock rows when it is run again by EXPLAIN ANALYZE.
+func isAnalyzable(sql string) bool {
+	return builder.Classify(sql).ReadOnly()
+}
+
+// isExplainable returns true if sql is a single statement which EXPLAIN accepts. Queries
+// with several statements are not explained, as EXPLAIN would apply to the first one only
+// and the others would be run again.
+func isExplainable(sql string) bool {
+	if builder.Classify(sql).Multiple {
+		return false
+	}
+	switch firstWord(sql) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH", "VALUES", "TABLE", "MERGE":
+		return true
+	}
+	return false
+}
+
+func firstWord(sql string) string {
+	sql = strings.TrimLeft(sql, " \t\r\n(")
+	if i := strings.IndexAny(sql, " \t\r\n("); i >= 0 {
+		sql = sql[:i]
+	}
+	return strings.ToUpper(sql)
+}
+
+// explainer returns the queryer used to explain statements run with q, or nil if they
+// can not be explained.
+func explainer(q interface{}) sqlx.QueryerContext {
+	switch q := q.(type) {
+	case stmtWrapper:
+		return nil // runs the prepared statement regardless of the query
+	case cachedRunner:
+		return q.db // keep EXPLAIN out of the statement cache
+	case sqlx.QueryerContext:
+		return q
+	}
+	return nil
+}
+
+// canExplain returns true if the plan of the statement can be captured. Dialect of DB is
+// checked rather than its driver, as it can be overridden.
+func (c *config) canExplain(q sqlx.QueryerContext, sql string, err error) bool {
+	return c.slow.opts.Explain && c.db != nil && c.db.Dialect == builder.Postgres && q != nil && err == nil && isExplainable(sql)
+}
@@ -0,0 +1,164 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"syreclabs.com/go/prequel/builder"
+)
+
+func TestSlowLogAllow(t *testing.T) {
+	s := &slowLog{opts: SlowQueryOptions{Limit: 2, Interval: time.Minute}}
+	now := time.Now()
+	for i, expected := range []bool{true, true, false, false} {
+		if got := s.allow(now.Add(time.Duration(i) * time.Second)); got != expected {
+			t.Errorf("call %d: expected %v, got %v", i, expected, got)
+		}
+	}
+	if !s.allow(now.Add(time.Minute)) {
+		t.Error("expected limit to be reset after interval")
+	}
+
+	s = &slowLog{opts: SlowQueryOptions{SampleRate: 1e-12}}
+	if s.allow(now) {
+		t.Error("expected statement to be sampled out")
+	}
+}
+
+func TestIsExplainable(t *testing.T) {
+	examples := []struct {
+		sql         string
+		explainable bool
+		analyzable  bool
+	}{
+		{"SELECT * FROM users", true, true},
+		{"(select 1) UNION (select 2)", true, true},
+		{"WITH u AS (SELECT * FROM users) SELECT * FROM u", true, true},
+		{"WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", true, false},
+		{"SELECT * FROM users FOR UPDATE", true, false},
+		{"SELECT * FROM users WHERE id IN (SELECT id FROM accounts FOR SHARE)", true, false},
+		{"DELETE FROM users", true, false},
+		{"insert into users (email) values ($1)", true, false},
+		{"CREATE TABLE t (id int)", false, false},
+		{"SELECT 1;", true, true},
+		{"SELECT 1; DELETE FROM users", false, false},
+		{"UPDATE users SET a = 1; DELETE FROM accounts", false, false},
+		{"SELECT ';' -- ; DELETE FROM users", true, true},
+		{"", false, false},
+	}
+	for _, x := range examples {
+		if got := isExplainable(x.sql); got != x.explainable {
+			t.Errorf("%q: expected explainable %v, got %v", x.sql, x.explainable, got)
+		}
+		if got := isAnalyzable(x.sql); got != x.analyzable {
+			t.Errorf("%q: expected analyzable %v, got %v", x.sql, x.analyzable, got)
+		}
+	}
+}
+
+func TestSlowQueryEvents(t *testing.T) {
+	var events []*QueryEvent
+	cfg := newConfig([]Option{
+		WithLogger(&captureLogger{}),
+		WithHooks(func(ctx context.Context, e *QueryEvent) { events = append(events, e) }),
+		WithSlowQueryThreshold(time.Nanosecond),
+		WithSlowQueryOptions(SlowQueryOptions{Limit: 1, Interval: time.Hour}),
+	})
+
+	ctx := context.Background()
+	doExecRaw(ctx, cfg, &recordingExecer{}, "DELETE FROM users")
+	doExecRaw(ctx, cfg, &recordingExecer{}, "DELETE FROM users")
+	if len(events) != 2 || !events[0].Slow || events[1].Slow {
+		t.Fatalf("expected only first event to be slow, got %v", events)
+	}
+	if events[0].Start.IsZero() || events[0].Plan != "" {
+		t.Errorf("expected start time and no plan, got %+v", events[0])
+	}
+}
+
+func TestExplainSlowQueries(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		var events []*QueryEvent
+		pdb := newDB(db.DB, "postgres", []Option{
+			WithHooks(func(ctx context.Context, e *QueryEvent) { events = append(events, e) }),
+			WithSlowQueryThreshold(time.Nanosecond),
+			WithSlowQueryOptions(SlowQueryOptions{Explain: true, Analyze: true}),
+		})
+
+		var users []User
+		if err := pdb.Select(ctx, builder.Select("*").From("users").Where("email = $1", "john@mail.net"), &users); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pdb.Exec(ctx, builder.Delete("users").Where("email = $1", "john@mail.net")); err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("expected %d events, got %d", 2, len(events))
+		}
+		if plan := events[0].Plan; !strings.HasPrefix(plan, "[") || !strings.Contains(plan, "Actual Total Time") {
+			t.Errorf("expected analyzed plan of SELECT, got %q", plan)
+		}
+		if plan := events[1].Plan; !strings.HasPrefix(plan, "[") || strings.Contains(plan, "Actual Total Time") {
+			t.Errorf("expected plan of DELETE not to be analyzed, got %q", plan)
+		}
+	})
+}
+
+func TestExplainInTx(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		var events []*QueryEvent
+		pdb := newDB(db.DB, "postgres", []Option{
+			WithLogger(&captureLogger{}),
+			WithHooks(func(ctx context.Context, e *QueryEvent) { events = append(events, e) }),
+			WithSlowQueryThreshold(time.Nanosecond),
+			WithSlowQueryOptions(SlowQueryOptions{Explain: true, Analyze: true}),
+		})
+
+		tx := pdb.MustBegin(ctx)
+		defer tx.Rollback()
+		tx.MustExecRaw(ctx, "CREATE TEMPORARY SEQUENCE explain_seq")
+
+		// the statement succeeds, but divides by zero when it is analyzed
+		var n int
+		if err := tx.GetRaw(ctx, &n, "SELECT 1 / (2 - nextval('explain_seq'))::int"); err != nil {
+			t.Fatal(err)
+		}
+		if e := events[len(events)-1]; !e.Slow || e.Plan != "" {
+			t.Fatalf("expected EXPLAIN to fail, got %+v", e)
+		}
+		if err := tx.GetRaw(ctx, &n, "SELECT 1"); err != nil {
+			t.Fatalf("expected transaction to be usable, got %v", err)
+		}
+		if e := events[len(events)-1]; e.Plan == "" {
+			t.Errorf("expected plan of statement following failed EXPLAIN, got %+v", e)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestCanExplain(t *testing.T) {
+	sqldb, err := sql.Open("postgres", "postgres://localhost/prequel?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqldb.Close()
+
+	pdb := NewDB(sqldb, "postgres", WithSlowQueryOptions(SlowQueryOptions{Explain: true}))
+	if !pdb.cfg.canExplain(pdb.DB, "SELECT 1", nil) {
+		t.Error("expected statement of PostgreSQL DB to be explained")
+	}
+	if pdb.cfg.canExplain(pdb.DB, "UPDATE a SET b = 1; DELETE FROM b", nil) {
+		t.Error("expected multiple statements not to be explained")
+	}
+	pdb.Dialect = builder.MySQL
+	if pdb.cfg.canExplain(pdb.DB, "SELECT 1", nil) {
+		t.Error("expected overridden dialect to disable plan capture")
+	}
+}
@@ -0,0 +1,338 @@
+package prequel
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"syreclabs.com/go/prequel/builder"
+)
+
+// Stmt is a wrapper around sqlx.Stmt which supports builder.Builder. Builders passed
+// to Select, Get and Exec must produce the same SQL as the one the statement was
+// prepared with, only their parameters are used. Stmt is safe for concurrent use.
+type Stmt struct {
+	Stmt    *sqlx.Stmt
+	sql     string
+	dialect builder.Dialect
+	cfg     *config
+}
+
+// Prepare creates a prepared statement for the SQL built by b using this DB.
+func (db *DB) Prepare(ctx context.Context, b builder.Builder) (*Stmt, error) {
+	return prepare(ctx, db.cfg, db.DB, db.Dialect, b)
+}
+
+// Prepare creates a prepared statement for the SQL built by b using this transaction.
+// The statement is closed when the transaction is committed or rolled back.
+func (tx *Tx) Prepare(ctx context.Context, b builder.Builder) (*Stmt, error) {
+	return prepare(ctx, tx.cfg, tx.Tx, tx.dialect, b)
+}
+
+// Prepare creates a prepared statement for the SQL built by b using this connection.
+func (conn *Conn) Prepare(ctx context.Context, b builder.Builder) (*Stmt, error) {
+	return prepare(ctx, conn.cfg, conn.Conn, conn.dialect, b)
+}
+
+// Stmt returns a transaction-specific statement from a statement prepared with DB or Conn.
+func (tx *Tx) Stmt(ctx context.Context, stmt *Stmt) *Stmt {
+	return &Stmt{Stmt: tx.Tx.StmtxContext(ctx, stmt.Stmt), sql: stmt.sql, dialect: stmt.dialect, cfg: stmt.cfg}
+}
+
+type preparer interface {
+	PreparexContext(ctx context.Context, query string) (*sqlx.Stmt, error)
+}
+
+func prepare(ctx context.Context, cfg *config, p preparer, d builder.Dialect, b builder.Builder) (*Stmt, error) {
+	sql, _, err := builder.BuildDialect(d, b)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	s, err := p.PreparexContext(ctx, sql)
+	cfg.logQuery(ctx, start, KindPrepare, sql, nil, -1, err)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{Stmt: s, sql: sql, dialect: d, cfg: cfg}, nil
+}
+
+// SQL returns the SQL text of the statement.
+func (stmt *Stmt) SQL() string {
+	return stmt.sql
+}
+
+// Close closes the statement.
+func (stmt *Stmt) Close() error {
+	return stmt.Stmt.Close()
+}
+
+// Select using this Stmt.
+func (stmt *Stmt) Select(ctx context.Context, b builder.Builder, dest interface{}) error {
+	return doSelect(ctx, stmt.cfg, stmtWrapper{stmt.Stmt}, stmtBuilder{stmt, b}, dest)
+}
+
+// Get using this Stmt.
+func (stmt *Stmt) Get(ctx context.Context, b builder.Builder, dest interface{}) error {
+	return doGet(ctx, stmt.cfg, stmtWrapper{stmt.Stmt}, stmtBuilder{stmt, b}, dest)
+}
+
+// Exec using this Stmt.
+func (stmt *Stmt) Exec(ctx context.Context, b builder.Builder) (sql.Result, error) {
+	return doExec(ctx, stmt.cfg, stmtWrapper{stmt.Stmt}, stmtBuilder{stmt, b})
+}
+
+// MustExec using this Stmt. This method will panic on error.
+func (stmt *Stmt) MustExec(ctx context.Context, b builder.Builder) sql.Result {
+	return doMustExec(ctx, stmt.cfg, stmtWrapper{stmt.Stmt}, stmtBuilder{stmt, b})
+}
+
+// stmtBuilder builds b with the statement dialect and checks that it produces
+// the prepared SQL.
+type stmtBuilder struct {
+	stmt *Stmt
+	b    builder.Builder
+}
+
+func (sb stmtBuilder) Build() (string, []interface{}, error) {
+	sql, params, err := builder.BuildDialect(sb.stmt.dialect, sb.b)
+	if err != nil {
+		return "", nil, err
+	}
+	if sql != sb.stmt.sql {
+		return "", nil, fmt.Errorf("query does not match prepared statement: %s", sql)
+	}
+	return sql, params, nil
+}
+
+// stmtWrapper is an unexported wrapper which implements sqlx.QueryerContext and
+// sqlx.ExecerContext by delegating to the underlying sqlx.Stmt.
+type stmtWrapper struct{ *sqlx.Stmt }
+
+func (w stmtWrapper) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return w.Stmt.QueryContext(ctx, args...)
+}
+
+func (w stmtWrapper) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	return w.Stmt.QueryxContext(ctx, args...)
+}
+
+func (w stmtWrapper) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	return w.Stmt.QueryRowxContext(ctx, args...)
+}
+
+func (w stmtWrapper) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return w.Stmt.ExecContext(ctx, args...)
+}
+
+// CacheStatements enables caching of up to size prepared statements, keyed by SQL text,
+// for builder queries run with Select, Get, Exec, Query and Each of this DB. The least
+// recently used statement is closed when the cache is full. Statements are invalidated
+// and prepared again when PostgreSQL reports that the cached plan must not change result
+// type, e.g. after the table has been altered. Queries run in transactions, connections
+// and Raw methods are not cached. Zero size disables the cache. Statements of the previous
+// cache are closed once they are no longer in use.
+func (db *DB) CacheStatements(size int) {
+	var c *stmtCache
+	if size > 0 {
+		c = newStmtCache(size, db.cfg)
+	}
+	db.stmtsMu.Lock()
+	old := db.stmts
+	db.stmts = c
+	db.stmtsMu.Unlock()
+	if old != nil {
+		old.close()
+	}
+}
+
+// queryExecer is implemented by sqlx.DB and cachedRunner.
+type queryExecer interface {
+	sqlx.QueryerContext
+	sqlx.ExecerContext
+}
+
+// runner returns the queryer and execer for builder queries of this DB.
+func (db *DB) runner() queryExecer {
+	db.stmtsMu.RLock()
+	c := db.stmts
+	db.stmtsMu.RUnlock()
+	if c == nil {
+		return db.DB
+	}
+	return cachedRunner{c, db.DB}
+}
+
+type stmtCache struct {
+	size int
+	cfg  *config
+
+	mu     sync.Mutex
+	lru    *list.List // of *cachedStmt, most recently used first
+	items  map[string]*list.Element
+	closed bool
+}
+
+type cachedStmt struct {
+	sql     string
+	stmt    *sqlx.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(size int, cfg *config) *stmtCache {
+	return &stmtCache{size: size, cfg: cfg, lru: list.New(), items: make(map[string]*list.Element)}
+}
+
+// acquire returns the cached statement for query, preparing it if needed. The statement
+// must be released after use, evicted statements are closed once they are released.
+func (c *stmtCache) acquire(ctx context.Context, db *sqlx.DB, query string) (*cachedStmt, error) {
+	c.mu.Lock()
+	if e, ok := c.items[query]; ok {
+		c.lru.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.refs++
+		c.mu.Unlock()
+		return cs, nil
+	}
+	c.mu.Unlock()
+
+	start := time.Now()
+	stmt, err := db.PreparexContext(ctx, query)
+	c.cfg.logQuery(ctx, start, KindPrepare, query, nil, -1, err)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[query]; ok { // prepared concurrently
+		stmt.Close()
+		c.lru.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.refs++
+		return cs, nil
+	}
+	if c.closed { // replaced while preparing, close the statement once it is released
+		return &cachedStmt{sql: query, stmt: stmt, refs: 1, evicted: true}, nil
+	}
+	cs := &cachedStmt{sql: query, stmt: stmt, refs: 1}
+	c.items[query] = c.lru.PushFront(cs)
+	for c.lru.Len() > c.size {
+		c.evict(c.lru.Back().Value.(*cachedStmt))
+	}
+	return cs, nil
+}
+
+func (c *stmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs.refs--
+	if cs.evicted && cs.refs == 0 {
+		cs.stmt.Close()
+	}
+}
+
+// invalidate removes cs from the cache unless it has already been replaced.
+func (c *stmtCache) invalidate(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !cs.evicted {
+		c.evict(cs)
+	}
+}
+
+// close evicts all statements. Statements which are in use are closed once they are
+// released.
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for c.lru.Len() > 0 {
+		c.evict(c.lru.Back().Value.(*cachedStmt))
+	}
+}
+
+// evict must be called with mu held.
+func (c *stmtCache) evict(cs *cachedStmt) {
+	c.lru.Remove(c.items[cs.sql])
+	delete(c.items, cs.sql)
+	cs.evicted = true
+	if cs.refs == 0 {
+		cs.stmt.Close()
+	}
+}
+
+// do calls fn with the cached statement for query. If the statement fails because its
+// plan is stale, it is prepared again and fn is retried once.
+func (c *stmtCache) do(ctx context.Context, db *sqlx.DB, query string, fn func(*sqlx.Stmt) error) error {
+	for retry := false; ; retry = true {
+		cs, err := c.acquire(ctx, db, query)
+		if err != nil {
+			return err
+		}
+		err = fn(cs.stmt)
+		c.release(cs)
+		if retry || !isStalePlan(err) {
+			return err
+		}
+		c.invalidate(cs)
+	}
+}
+
+// isStalePlan returns true if err reports that a prepared statement must be prepared again.
+func isStalePlan(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "cached plan must not change result type")
+}
+
+// cachedRunner implements sqlx.QueryerContext and sqlx.ExecerContext using statements
+// from the cache. Statements stay valid while rows returned by them are open.
+type cachedRunner struct {
+	c  *stmtCache
+	db *sqlx.DB
+}
+
+func (r cachedRunner) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := r.c.do(ctx, r.db, query, func(s *sqlx.Stmt) (err error) {
+		rows, err = s.QueryContext(ctx, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (r cachedRunner) QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error) {
+	var rows *sqlx.Rows
+	err := r.c.do(ctx, r.db, query, func(s *sqlx.Stmt) (err error) {
+		rows, err = s.QueryxContext(ctx, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (r cachedRunner) QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row {
+	var row *sqlx.Row
+	r.c.do(ctx, r.db, query, func(s *sqlx.Stmt) error {
+		row = s.QueryRowxContext(ctx, args...)
+		return row.Err()
+	})
+	if row == nil {
+		// the statement could not be prepared, let the query report the error
+		return r.db.QueryRowxContext(ctx, query, args...)
+	}
+	return row
+}
+
+func (r cachedRunner) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result
+	err := r.c.do(ctx, r.db, query, func(s *sqlx.Stmt) (err error) {
+		res, err = s.ExecContext(ctx, args...)
+		return err
+	})
+	return res, err
+}
@@ -0,0 +1,166 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"syreclabs.com/go/prequel/builder"
+)
+
+func TestPrepare(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		byEmail := func(email string) builder.Builder {
+			return builder.Select("*").From("users").Where("email = $1", email)
+		}
+		stmt, err := db.Prepare(ctx, byEmail(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stmt.Close()
+
+		for _, email := range []string{"user@example.com", "john@mail.net"} {
+			var user User
+			if err := stmt.Get(ctx, byEmail(email), &user); err != nil {
+				t.Fatal(err)
+			}
+			if user.Email != email {
+				t.Errorf("expected email %q, got %q", email, user.Email)
+			}
+		}
+
+		var users []User
+		if err := stmt.Select(ctx, byEmail("nobody@example.com"), &users); err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 0 {
+			t.Errorf("expected no records, got %d", len(users))
+		}
+
+		other := builder.Select("*").From("users").Where("last_name = $1", "Doe")
+		if err := stmt.Select(ctx, other, &users); err == nil {
+			t.Error("expected statement mismatch error, got nil")
+		}
+
+		// transaction-specific statement
+		del, err := db.Prepare(ctx, builder.Delete("users").Where("email = $1", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer del.Close()
+
+		tx := db.MustBegin(ctx)
+		defer tx.Rollback()
+		res := tx.Stmt(ctx, del).MustExec(ctx, builder.Delete("users").Where("email = $1", "john@mail.net"))
+		if n, _ := res.RowsAffected(); n != 1 {
+			t.Errorf("expected %d affected rows, got %d", 1, n)
+		}
+	})
+}
+
+func TestCacheStatements(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		db.CacheStatements(2)
+		defer db.CacheStatements(0)
+
+		for _, b := range []builder.Builder{
+			builder.Select("id").From("users").Where("email = $1", "user@example.com"),
+			builder.Select("id").From("users").Where("last_name = $1", "Doe"),
+			builder.Select("id").From("users").Where("email = $1", "john@mail.net"),
+		} {
+			var id int
+			if err := db.Get(ctx, b, &id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := db.stmts.lru.Len(); n != 2 {
+			t.Fatalf("expected %d cached statements, got %d", 2, n)
+		}
+
+		// altering the table changes result type of the cached SELECT * statement
+		all := builder.Select("*").From("users").OrderBy("id")
+		var users []User
+		if err := db.Select(ctx, all, &users); err != nil {
+			t.Fatal(err)
+		}
+		db.MustExecRaw(ctx, "ALTER TABLE users DROP COLUMN created_at")
+		var rows []struct {
+			Id        int    `db:"id"`
+			FirstName string `db:"first_name"`
+			LastName  string `db:"last_name"`
+			Email     string `db:"email"`
+		}
+		if err := db.Select(ctx, all, &rows); err != nil {
+			t.Fatalf("expected stale statement to be prepared again, got %v", err)
+		}
+		if len(rows) != 3 {
+			t.Errorf("expected %d records, got %d", 3, len(rows))
+		}
+
+		// replacing the cache closes its statements
+		var cached []*sqlx.Stmt
+		for e := db.stmts.lru.Front(); e != nil; e = e.Next() {
+			cached = append(cached, e.Value.(*cachedStmt).stmt)
+		}
+		db.CacheStatements(0)
+		for _, stmt := range cached {
+			if _, err := stmt.ExecContext(ctx, "user@example.com"); err == nil || !strings.Contains(err.Error(), "closed") {
+				t.Errorf("expected statement to be closed, got %v", err)
+			}
+		}
+	})
+}
+
+func TestCacheStatementsConcurrently(t *testing.T) {
+	sqldb, err := sql.Open("postgres", "postgres://localhost/prequel?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqldb.Close()
+
+	pdb := NewDB(sqldb, "postgres")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(size int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pdb.CacheStatements(size)
+				pdb.runner()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	old := newStmtCache(1, nil)
+	pdb.stmts = old
+	pdb.CacheStatements(0)
+	if pdb.runner() != pdb.DB || !old.closed {
+		t.Error("expected cache to be disabled and closed")
+	}
+}
+
+func TestIsStalePlan(t *testing.T) {
+	examples := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{errors.New("failed"), false},
+		{&pq.Error{Code: "0A000", Message: "cached plan must not change result type"}, true},
+	}
+	for _, x := range examples {
+		if got := isStalePlan(x.err); got != x.expected {
+			t.Errorf("%v: expected %v, got %v", x.err, x.expected, got)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultMaxRetries is the number of retries used by RunInTx if options are not specified.
+const DefaultMaxRetries = 3
+
+// RunTxOptions holds the options used in RunInTx.
+type RunTxOptions struct {
+	// TxOptions are passed to BeginTx.
+	TxOptions *sql.TxOptions
+	// MaxRetries is the maximum number of times the function is retried after
+	// a serialization failure or deadlock. Zero disables retries.
+	MaxRetries int
+	// Backoff returns the delay before the given retry, starting with 0.
+	// DefaultBackoff is used if nil.
+	Backoff func(retry int) time.Duration
+}
+
+// DefaultBackoff returns exponential delay starting with 10ms and capped at 1s,
+// with up to 50% random jitter.
+func DefaultBackoff(retry int) time.Duration {
+	d := time.Second
+	if retry < 7 {
+		d = 10 * time.Millisecond << uint(retry)
+	}
+	return d + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// RunInTx runs fn in a new transaction started with BeginTx using this DB. The transaction
+// is committed if fn returns nil and rolled back if it returns an error, panics or calls
+// runtime.Goexit. A panic is re-raised after rollback. The whole transaction is retried
+// with backoff on serialization failures (SQLSTATE 40001) and deadlocks (40P01). If opts
+// is nil, DefaultMaxRetries and DefaultBackoff are used.
+//
+// The context passed to fn carries the transaction (see WithRunner). If ctx already carries
+// a transaction of this DB, fn joins it using a nested transaction instead: retries are left
+// to the outermost RunInTx as the whole transaction has to be rerun, and TxOptions must be
+// nil or match the isolation level and access mode of the joined transaction. If ctx
+// carries a connection of this DB, the transaction is started using it.
+func (db *DB) RunInTx(ctx context.Context, opts *RunTxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	return runInTx(ctx, db.cfg, db, db, opts, fn)
+}
+
+// RunInTx runs fn in a new transaction started with BeginTx using this connection.
+// See DB.RunInTx for details.
+func (conn *Conn) RunInTx(ctx context.Context, opts *RunTxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	return runInTx(ctx, conn.cfg, conn.db, conn, opts, fn)
+}
+
+// RunInTx runs fn in a nested transaction using this transaction. Retries are left to
+// the outermost RunInTx and TxOptions must be nil or match those of this transaction.
+// See DB.RunInTx for details.
+func (tx *Tx) RunInTx(ctx context.Context, opts *RunTxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	return joinTx(ctx, tx, opts, fn)
+}
+
+// runInTx runs fn in a transaction begun with b, or joins the transaction of db carried
+// by ctx.
+func runInTx(ctx context.Context, cfg *config, db *DB, b Beginner, opts *RunTxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	switch s := sessionFor(ctx, db).(type) {
+	case *Tx:
+		return joinTx(ctx, s, opts, fn)
+	case *Conn:
+		if _, ok := b.(*DB); ok {
+			b = s // begin on the connection rather than a new one from the pool
+		}
+	}
+	if opts == nil {
+		opts = &RunTxOptions{MaxRetries: DefaultMaxRetries}
+	}
+	return retryTx(ctx, cfg, opts, func() error {
+		return runTxOnce(ctx, b, opts.TxOptions, fn)
+	})
+}
+
+// joinTx runs fn in a nested transaction of tx if opts are compatible with tx.
+func joinTx(ctx context.Context, tx *Tx, opts *RunTxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	if opts != nil && !tx.root().accepts(opts.TxOptions) {
+		return errors.New("transaction options differ from those of the joined transaction")
+	}
+	return runTxOnce(ctx, tx, nil, fn)
+}
+
+// runTxOnce runs fn in a transaction and commits it, or rolls it back on error or panic.
+func runTxOnce(ctx context.Context, b Beginner, txOpts *sql.TxOptions, fn func(ctx context.Context, tx *Tx) error) error {
+	tx, err := b.BeginTx(ctx, txOpts)
+	if err != nil {
+		return err
+	}
+	returned := false
+	defer func() {
+		// fn panicked, possibly with nil, or called runtime.Goexit, which keeps unwinding
+		if !returned {
+			tx.Rollback()
+		}
+	}()
+	err = fn(WithRunner(ctx, tx), tx)
+	returned = true
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// retryTx calls run until it succeeds, fails with an error which is not retryable
+// or retries are exhausted. Context cancellation stops waiting for the next retry.
+func retryTx(ctx context.Context, cfg *config, opts *RunTxOptions, run func() error) error {
+	backoff := opts.Backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+	for retry := 0; ; retry++ {
+		err := run()
+		if err == nil || retry >= opts.MaxRetries || !isRetryable(err) {
+			return err
+		}
+
+		d := backoff(retry)
+		cfg.logf(time.Now(), "RETRY %d/%d in %v [%v]", retry+1, opts.MaxRetries, d, err)
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// isRetryable returns true if err is a serialization failure or deadlock.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	}
+	return false
+}
+
+// Savepoint creates a savepoint with the given name in this transaction.
+func (tx *Tx) Savepoint(ctx context.Context, name string) error {
+	if err := tx.execSavepoint(ctx, "SAVEPOINT "+tx.dialect.Ident(name)); err != nil {
+		return err
+	}
+	tx.marks = append(tx.marks, savepointMark{name, len(tx.onCommit), len(tx.onRollback)})
+	return nil
+}
+
+// RollbackTo rolls back this transaction to the savepoint with the given name.
+// The savepoint remains valid and can be rolled back to again. Hooks registered after
+// the savepoint are discarded.
+func (tx *Tx) RollbackTo(ctx context.Context, name string) error {
+	if err := tx.execSavepoint(ctx, "ROLLBACK TO SAVEPOINT "+tx.dialect.Ident(name)); err != nil {
+		return err
+	}
+	if i := tx.mark(name); i >= 0 {
+		m := tx.marks[i]
+		tx.onCommit = tx.onCommit[:m.onCommit]
+		tx.onRollback = tx.onRollback[:m.onRollback]
+		tx.marks = tx.marks[:i+1] // later savepoints are destroyed
+	}
+	return nil
+}
+
+// Release releases the savepoint with the given name, keeping the changes made after it.
+func (tx *Tx) Release(ctx context.Context, name string) error {
+	if err := tx.execSavepoint(ctx, "RELEASE SAVEPOINT "+tx.dialect.Ident(name)); err != nil {
+		return err
+	}
+	if i := tx.mark(name); i >= 0 {
+		tx.marks = tx.marks[:i] // later savepoints are released too
+	}
+	return nil
+}
+
+func (tx *Tx) execSavepoint(ctx context.Context, query string) error {
+	defer tx.cfg.logf(time.Now(), "%s", query)
+	_, err := tx.Tx.ExecContext(ctx, query)
+	return err
+}
+
+// Begin starts a nested transaction using a savepoint in this transaction.
+// Commit of the nested transaction releases the savepoint and Rollback rolls
+// back to it, leaving this transaction active.
+func (tx *Tx) Begin(ctx context.Context) (*Tx, error) {
+	return tx.BeginTx(ctx, nil)
+}
+
+// BeginTx starts a nested transaction using a savepoint in this transaction.
+// As savepoints share the transaction isolation level and access mode, opts
+// must be nil or default.
+func (tx *Tx) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if opts != nil && (opts.Isolation != sql.LevelDefault || opts.ReadOnly) {
+		return nil, errors.New("nested transaction does not support options")
+	}
+	if tx.done {
+		return nil, sql.ErrTxDone
+	}
+
+	root := tx.root()
+	root.savepoints++
+	name := "sp_" + strconv.Itoa(root.savepoints)
+
+	if err := tx.Savepoint(ctx, name); err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx.Tx, dialect: tx.dialect, cfg: tx.cfg, db: tx.db, parent: tx, ctx: ctx, savepoint: name}, nil
+}
+
+// root returns the outermost transaction of this nested transaction.
+func (tx *Tx) root() *Tx {
+	for tx.parent != nil {
+		tx = tx.parent
+	}
+	return tx
+}
+
+// accepts returns true if a transaction begun with opts can join this root transaction,
+// i.e. opts are nil, or have the same access mode and the same or default isolation level.
+func (tx *Tx) accepts(opts *sql.TxOptions) bool {
+	if opts == nil {
+		return true
+	}
+	var cur sql.TxOptions
+	if tx.opts != nil {
+		cur = *tx.opts
+	}
+	return opts.ReadOnly == cur.ReadOnly && (opts.Isolation == sql.LevelDefault || opts.Isolation == cur.Isolation)
+}
+
+// MustBegin starts a nested transaction using this transaction. This method will panic on error.
+func (tx *Tx) MustBegin(ctx context.Context) *Tx {
+	ntx, err := tx.Begin(ctx)
+	if err != nil {
+		panic(err)
+	}
+	return ntx
+}
+
+// MustBeginTx starts a nested transaction using this transaction. This method will panic on error.
+func (tx *Tx) MustBeginTx(ctx context.Context, opts *sql.TxOptions) *Tx {
+	ntx, err := tx.BeginTx(ctx, opts)
+	if err != nil {
+		panic(err)
+	}
+	return ntx
+}
+
+// savepointMark records the number of hooks registered before a savepoint.
+type savepointMark struct {
+	name       string
+	onCommit   int
+	onRollback int
+}
+
+// OnCommit registers fn to be called after this transaction is committed. Hooks are
+// called in order of registration. Hooks of a nested transaction are called when the
+// outermost transaction commits, and are discarded if the nested transaction, or
+// a savepoint created before the hook was registered, is rolled back.
+func (tx *Tx) OnCommit(fn func()) {
+	tx.onCommit = append(tx.onCommit, fn)
+}
+
+// OnRollback registers fn to be called after this transaction is rolled back. Hooks
+// are called in order of registration and are discarded along with OnCommit hooks
+// when a nested transaction or savepoint is rolled back.
+func (tx *Tx) OnRollback(fn func()) {
+	tx.onRollback = append(tx.onRollback, fn)
+}
+
+func runHooks(hooks []func()) {
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// mark returns the index of the most recent savepoint mark with the given name, or -1.
+func (tx *Tx) mark(name string) int {
+	for i := len(tx.marks) - 1; i >= 0; i-- {
+		if tx.marks[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,291 @@
+package prequel
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"syreclabs.com/go/prequel/builder"
+)
+
+func TestRetryTx(t *testing.T) {
+	noBackoff := func(int) time.Duration { return 0 }
+
+	examples := []struct {
+		name     string
+		errs     []error
+		retries  int
+		expected int
+	}{
+		{"Success", []error{nil}, 3, 1},
+		{"SerializationFailure", []error{&pq.Error{Code: "40001"}, nil}, 3, 2},
+		{"Deadlock", []error{&pq.Error{Code: "40P01"}, &pq.Error{Code: "40P01"}, nil}, 3, 3},
+		{"WrappedError", []error{fmt.Errorf("commit: %w", &pq.Error{Code: "40001"}), nil}, 3, 2},
+		{"Exhausted", []error{&pq.Error{Code: "40001"}, &pq.Error{Code: "40001"}, &pq.Error{Code: "40001"}}, 2, 3},
+		{"NoRetries", []error{&pq.Error{Code: "40001"}}, 0, 1},
+		{"NotRetryable", []error{&pq.Error{Code: "23505"}}, 3, 1},
+		{"OtherError", []error{errors.New("failed")}, 3, 1},
+	}
+	for _, x := range examples {
+		t.Run(x.name, func(t *testing.T) {
+			calls := 0
+			err := retryTx(context.Background(), nil, &RunTxOptions{MaxRetries: x.retries, Backoff: noBackoff}, func() error {
+				err := x.errs[calls]
+				calls++
+				return err
+			})
+			if calls != x.expected {
+				t.Errorf("expected %d calls, got %d", x.expected, calls)
+			}
+			if err != x.errs[len(x.errs)-1] {
+				t.Errorf("expected err to be %v, got %v", x.errs[len(x.errs)-1], err)
+			}
+		})
+	}
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calls := 0
+		err := retryTx(ctx, nil, &RunTxOptions{MaxRetries: 3}, func() error {
+			calls++
+			return &pq.Error{Code: "40001"}
+		})
+		if calls != 1 || err == nil {
+			t.Errorf("expected single failed call, got %d calls and %v", calls, err)
+		}
+	})
+}
+
+func TestTxEndWrapped(t *testing.T) {
+	// Tx created by wrapping sqlx.Tx has no config, context or cancel func
+	tx := &Tx{}
+	called := false
+	if err := tx.end(KindCommit, func() error { called = true; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatal("expected commit to be called")
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	for retry, min := range []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond} {
+		if d := DefaultBackoff(retry); d < min || d > min*3/2 {
+			t.Errorf("retry %d: expected delay within [%v, %v], got %v", retry, min, min*3/2, d)
+		}
+	}
+	if d := DefaultBackoff(100); d < time.Second || d > 3*time.Second/2 {
+		t.Errorf("expected delay to be capped, got %v", d)
+	}
+}
+
+func TestRunInTx(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		count := func() int {
+			var n int
+			if err := db.GetRaw(ctx, &n, "SELECT count(*) FROM users"); err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+		del := builder.Delete("users").Where("email = $1", "user@example.com")
+
+		// rollback on error
+		failed := errors.New("failed")
+		err := db.RunInTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+			if _, err := tx.Exec(ctx, del); err != nil {
+				return err
+			}
+			return failed
+		})
+		if err != failed {
+			t.Fatalf("expected err to be %v, got %v", failed, err)
+		}
+		if n := count(); n != 3 {
+			t.Fatalf("expected %d records, got %d", 3, n)
+		}
+
+		// rollback on panic
+		func() {
+			defer func() {
+				if p := recover(); p != "boom" {
+					t.Fatalf("expected panic to be re-raised, got %v", p)
+				}
+			}()
+			db.RunInTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+				tx.MustExec(ctx, del)
+				panic("boom")
+			})
+		}()
+		if n := count(); n != 3 {
+			t.Fatalf("expected %d records, got %d", 3, n)
+		}
+
+		// rollback on runtime.Goexit, e.g. t.FailNow in fn
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			db.RunInTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+				tx.MustExec(ctx, del)
+				runtime.Goexit()
+				return nil
+			})
+		}()
+		<-done
+		if n := count(); n != 3 {
+			t.Fatalf("expected %d records, got %d", 3, n)
+		}
+
+		// commit
+		if err := db.RunInTx(ctx, nil, func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec(ctx, del)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(); n != 2 {
+			t.Fatalf("expected %d records, got %d", 2, n)
+		}
+	})
+}
+
+func TestNestedTx(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		loadFixtures(ctx)
+
+		count := func(r Runner) int {
+			var n int
+			if err := r.GetRaw(ctx, &n, "SELECT count(*) FROM users"); err != nil {
+				t.Fatal(err)
+			}
+			return n
+		}
+		del := func(email string) builder.Builder {
+			return builder.Delete("users").Where("email = $1", email)
+		}
+
+		tx := db.MustBegin(ctx)
+		defer tx.Rollback()
+
+		// rolled back nested transaction
+		ntx := tx.MustBegin(ctx)
+		ntx.MustExec(ctx, del("user@example.com"))
+		if n := count(ntx); n != 2 {
+			t.Fatalf("expected %d records, got %d", 2, n)
+		}
+		if err := ntx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ntx.Commit(); err != sql.ErrTxDone {
+			t.Fatalf("expected err to be %v, got %v", sql.ErrTxDone, err)
+		}
+		if n := count(tx); n != 3 {
+			t.Fatalf("expected %d records, got %d", 3, n)
+		}
+
+		// committed nested transaction inside another nested transaction
+		ntx = tx.MustBegin(ctx)
+		nntx := ntx.MustBegin(ctx)
+		nntx.MustExec(ctx, del("john@mail.net"))
+		if err := nntx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if err := ntx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(tx); n != 2 {
+			t.Fatalf("expected %d records, got %d", 2, n)
+		}
+
+		// explicit savepoints
+		if err := tx.Savepoint(ctx, "before delete"); err != nil {
+			t.Fatal(err)
+		}
+		tx.MustExec(ctx, del("janie@email.com"))
+		if err := tx.RollbackTo(ctx, "before delete"); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Release(ctx, "before delete"); err != nil {
+			t.Fatal(err)
+		}
+		if n := count(tx); n != 2 {
+			t.Fatalf("expected %d records, got %d", 2, n)
+		}
+
+		if _, err := tx.BeginTx(ctx, &sql.TxOptions{ReadOnly: true}); err == nil {
+			t.Fatal("expected nested transaction options error, got nil")
+		}
+	})
+}
+
+func TestTxHooks(t *testing.T) {
+	withSchema(context.Background(), func(ctx context.Context) {
+		var calls []string
+		hook := func(name string) func() {
+			return func() { calls = append(calls, name) }
+		}
+
+		tx := db.MustBegin(ctx)
+		tx.OnCommit(hook("commit 1"))
+		tx.OnRollback(hook("rollback 1"))
+
+		// released nested transaction passes its hooks to the parent
+		ntx := tx.MustBegin(ctx)
+		ntx.OnCommit(hook("commit 2"))
+		if err := ntx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+
+		// rolled back nested transaction discards its hooks
+		ntx = tx.MustBegin(ctx)
+		ntx.OnCommit(hook("discarded"))
+		ntx.OnRollback(hook("discarded"))
+		if err := ntx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+
+		// rolled back savepoint discards hooks registered after it
+		if err := tx.Savepoint(ctx, "sp"); err != nil {
+			t.Fatal(err)
+		}
+		tx.OnCommit(hook("discarded"))
+		if err := tx.RollbackTo(ctx, "sp"); err != nil {
+			t.Fatal(err)
+		}
+		tx.OnCommit(hook("commit 3"))
+
+		if len(calls) != 0 {
+			t.Fatalf("expected hooks not to be called before commit, got %v", calls)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"commit 1", "commit 2", "commit 3"}
+		if fmt.Sprint(calls) != fmt.Sprint(expected) {
+			t.Fatalf("expected calls %v, got %v", expected, calls)
+		}
+
+		calls = nil
+		tx = db.MustBegin(ctx)
+		tx.OnCommit(hook("commit"))
+		tx.OnRollback(hook("rollback"))
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Rollback(); err == nil {
+			t.Fatal("expected second rollback to fail")
+		}
+		if fmt.Sprint(calls) != "[rollback]" {
+			t.Fatalf("expected calls %v, got %v", []string{"rollback"}, calls)
+		}
+	})
+}